@@ -0,0 +1,326 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/fishykins/gorustplus/pkg/rustmap"
+	"github.com/fishykins/gorustplus/pkg/rustplus"
+)
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(s *session, args []string) error
+}
+
+var commands []*command
+
+// Filled in by init, as the commands look themselves up to report usage.
+func init() {
+	commands = []*command{
+		{"info", "info", "server name, population and map", runInfo},
+		{"time", "time", "in-game time, sunrise and sunset", runTime},
+		{"team", "team", "team members and where they are", runTeam},
+		{"chat", "chat send <message> | chat tail [-n count]", "send to or follow team chat", runChat},
+		{"markers", "markers", "map markers such as vending machines and events", runMarkers},
+		{"map", "map --out map.png [--plain]", "render the map with the team and markers", runMap},
+		{"entity", "entity get <id|name> | entity set <id|name> <on|off>", "read or switch a paired entity", runEntity},
+		{"camera", "camera snapshot <identifier> [--out frame.jpg]", "save a frame from a CCTV camera", runCamera},
+		{"shell", "shell", "interactive shell with completion and live broadcasts", runShell},
+	}
+}
+
+func findCommand(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func usageError(c *command) error {
+	return fmt.Errorf("usage: rustplus %s", c.usage)
+}
+
+func runInfo(s *session, args []string) error {
+	info, err := s.info()
+	if err != nil {
+		return err
+	}
+	return s.out.message(info, func(w io.Writer) {
+		fmt.Fprintf(w, "%s\n", info.GetName())
+		fmt.Fprintf(w, "players: %d/%d (%d queued)\n", info.GetPlayers(), info.GetMaxPlayers(), info.GetQueuedPlayers())
+		fmt.Fprintf(w, "map:     %s, size %d, seed %d\n", info.GetMap(), info.GetMapSize(), info.GetSeed())
+		fmt.Fprintf(w, "wiped:   %s\n", time.Unix(int64(info.GetWipeTime()), 0).Format(time.RFC1123))
+		if info.GetUrl() != "" {
+			fmt.Fprintf(w, "url:     %s\n", info.GetUrl())
+		}
+	})
+}
+
+func runTime(s *session, args []string) error {
+	r, err := s.call(s.client.NewTimeRequest())
+	if err != nil {
+		return err
+	}
+	t := r.Time
+	clock := rustplus.NewGameClock(t)
+	return s.out.message(t, func(w io.Writer) {
+		fmt.Fprintf(w, "%s (sunrise %s, sunset %s)\n", clock, rustplus.FormatGameTime(t.GetSunrise()), rustplus.FormatGameTime(t.GetSunset()))
+		if clock.IsDay() {
+			fmt.Fprintf(w, "night in %s\n", clock.UntilNight().Round(time.Second))
+		} else {
+			fmt.Fprintf(w, "day in %s\n", clock.UntilDay().Round(time.Second))
+		}
+	})
+}
+
+func runTeam(s *session, args []string) error {
+	info, err := s.info()
+	if err != nil {
+		return err
+	}
+	r, err := s.call(s.client.NewTeamRequest())
+	if err != nil {
+		return err
+	}
+	team := r.TeamInfo
+	grid := rustplus.NewMapGrid(info.GetMapSize())
+	return s.out.message(team, func(w io.Writer) {
+		for _, m := range team.GetMembers() {
+			status := "offline"
+			if m.GetIsOnline() {
+				status = "online"
+			}
+			if !m.GetIsAlive() {
+				status += ", dead"
+			}
+			leader := ""
+			if m.GetSteamId() == team.GetLeaderSteamId() {
+				leader = " (leader)"
+			}
+			fmt.Fprintf(w, "%-20s %-4s %s%s\n", m.GetName(), grid.Grid(m.GetX(), m.GetY()), status, leader)
+		}
+	})
+}
+
+func runChat(s *session, args []string) error {
+	c := findCommand("chat")
+	if len(args) == 0 {
+		return usageError(c)
+	}
+	switch args[0] {
+	case "send":
+		if len(args) < 2 {
+			return usageError(c)
+		}
+		parts, err := rustplus.SplitMessage(strings.Join(args[1:], " "), rustplus.MaxChatMessageLength)
+		if err != nil {
+			return err
+		}
+		for _, part := range parts {
+			if _, err := s.call(s.client.NewChatWriteRequest(part)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "tail":
+		flags := flag.NewFlagSet("chat tail", flag.ContinueOnError)
+		count := flags.Int("n", 10, "number of earlier messages to show")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		return tailChat(s, *count)
+	}
+	return usageError(c)
+}
+
+func tailChat(s *session, count int) error {
+	// Subscribe before reading the history so nothing slips through the gap.
+	sub := s.client.SubscribeChat(64, rustplus.Block)
+	defer sub.Close()
+
+	r, err := s.call(s.client.NewChatReadRequest())
+	if err != nil {
+		return err
+	}
+	messages := r.TeamChat.GetMessages()
+	if len(messages) > count {
+		messages = messages[len(messages)-count:]
+	}
+	for _, m := range messages {
+		if err := printChat(s.out, m); err != nil {
+			return err
+		}
+	}
+	// The shell prints new messages as they arrive anyway.
+	if s.interactive {
+		return nil
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	for {
+		select {
+		case m := <-sub.C:
+			if err := printChat(s.out, m); err != nil {
+				return err
+			}
+		case err := <-s.closed:
+			return fmt.Errorf("connection closed: %s", err)
+		case <-interrupt:
+			return nil
+		}
+	}
+}
+
+func printChat(out *output, m *rustplus.AppChatMessage) error {
+	return out.message(m, func(w io.Writer) {
+		writeChat(w, m)
+	})
+}
+
+func writeChat(w io.Writer, m *rustplus.AppChatMessage) {
+	at := time.Unix(int64(m.GetTime()), 0).Format("15:04:05")
+	fmt.Fprintf(w, "%s %s: %s\n", at, m.GetName(), m.GetMessage())
+}
+
+func runMarkers(s *session, args []string) error {
+	info, err := s.info()
+	if err != nil {
+		return err
+	}
+	r, err := s.call(s.client.NewMarkersRequest())
+	if err != nil {
+		return err
+	}
+	markers := r.MapMarkers
+	grid := rustplus.NewMapGrid(info.GetMapSize())
+	return s.out.message(markers, func(w io.Writer) {
+		for _, m := range markers.GetMarkers() {
+			fmt.Fprintf(w, "%-10d %-16s %-4s %s\n", m.GetId(), m.GetType(), grid.Grid(m.GetX(), m.GetY()), m.GetName())
+		}
+	})
+}
+
+func runMap(s *session, args []string) error {
+	flags := flag.NewFlagSet("map", flag.ContinueOnError)
+	path := flags.String("out", "map.png", "file to write the png to")
+	plain := flags.Bool("plain", false, "leave out the team and markers")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	info, err := s.info()
+	if err != nil {
+		return err
+	}
+	r, err := s.call(s.client.NewMapRequest())
+	if err != nil {
+		return err
+	}
+	var team *rustplus.AppTeamInfo
+	var markers *rustplus.AppMapMarkers
+	if !*plain {
+		// The overlays are a nice to have, so a failure here still leaves a usable map.
+		if tr, err := s.call(s.client.NewTeamRequest()); err == nil {
+			team = tr.TeamInfo
+		}
+		if mr, err := s.call(s.client.NewMarkersRequest()); err == nil {
+			markers = mr.MapMarkers
+		}
+	}
+
+	f, err := os.Create(*path)
+	if err != nil {
+		return err
+	}
+	if err := rustmap.Render(f, r.Map, info.GetMapSize(), team, markers); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return s.out.value(map[string]string{"out": *path}, func(w io.Writer) {
+		fmt.Fprintf(w, "wrote %s\n", *path)
+	})
+}
+
+func runEntity(s *session, args []string) error {
+	c := findCommand("entity")
+	if len(args) < 2 {
+		return usageError(c)
+	}
+	device, err := s.device(args[1])
+	if err != nil {
+		return err
+	}
+	switch {
+	case args[0] == "get" && len(args) == 2:
+		r, err := s.call(s.client.NewDeviceGetRequest(device))
+		if err != nil {
+			return err
+		}
+		entity := r.EntityInfo
+		return s.out.message(entity, func(w io.Writer) {
+			payload := entity.GetPayload()
+			fmt.Fprintf(w, "%s %d: %s, value %t\n", entity.GetType(), device.GetId(), device.Name, payload.GetValue())
+			for _, item := range payload.GetItems() {
+				fmt.Fprintf(w, "  %6d x %s\n", item.GetQuantity(), rustplus.ItemName(item.GetItemId()))
+			}
+		})
+	case args[0] == "set" && len(args) == 3:
+		var state bool
+		switch strings.ToLower(args[2]) {
+		case "on", "true", "1":
+			state = true
+		case "off", "false", "0":
+		default:
+			return usageError(c)
+		}
+		_, err := s.call(s.client.NewDeviceSetRequest(device, state))
+		return err
+	}
+	return usageError(c)
+}
+
+func runCamera(s *session, args []string) error {
+	c := findCommand("camera")
+	if len(args) < 2 || args[0] != "snapshot" {
+		return usageError(c)
+	}
+	flags := flag.NewFlagSet("camera snapshot", flag.ContinueOnError)
+	path := flags.String("out", "", "file to write the jpeg to, defaults to <identifier>.jpg")
+	if err := flags.Parse(args[2:]); err != nil {
+		return err
+	}
+	identifier := args[1]
+	if *path == "" {
+		*path = identifier + ".jpg"
+	}
+
+	r, err := s.call(s.client.NewCameraRequest(identifier, 0))
+	if err != nil {
+		return err
+	}
+	if r.CameraFrame == nil || len(r.CameraFrame.GetJpgImage()) == 0 {
+		return errors.New("camera sent no frame")
+	}
+	if err := os.WriteFile(*path, r.CameraFrame.GetJpgImage(), 0644); err != nil {
+		return err
+	}
+	return s.out.value(map[string]interface{}{"out": *path, "frame": r.CameraFrame.GetFrame()}, func(w io.Writer) {
+		fmt.Fprintf(w, "wrote %s\n", *path)
+	})
+}
@@ -0,0 +1,72 @@
+// Command rustplus talks to a Rust+ server from the command line.
+//
+// Servers, player tokens and devices are read from a config file, see package config. Usage:
+//
+//	rustplus [-config rustplus.json] [-server name] [-json] [-timeout 10s] <command> [arguments]
+//
+// Run rustplus without a command to list the commands.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fishykins/gorustplus/pkg/config"
+)
+
+func main() {
+	flags := flag.NewFlagSet("rustplus", flag.ExitOnError)
+	configPath := flags.String("config", config.DefaultPath(), "config file with servers and player tokens")
+	serverName := flags.String("server", "", "server to use from the config, defaults to the first")
+	jsonOutput := flags.Bool("json", false, "print results as JSON, one document per line")
+	timeout := flags.Duration("timeout", 10*time.Second, "how long to wait for the server")
+	flags.Usage = func() { usage(flags) }
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		usage(flags)
+		os.Exit(2)
+	}
+	cmd := findCommand(flags.Arg(0))
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n", flags.Arg(0))
+		usage(flags)
+		os.Exit(2)
+	}
+
+	conf, err := config.Load(*configPath)
+	if err != nil {
+		fail(err)
+	}
+	server, err := conf.Server(*serverName)
+	if err != nil {
+		fail(err)
+	}
+	out := &output{w: os.Stdout, json: *jsonOutput}
+	s, err := connect(server, out, *timeout)
+	if err != nil {
+		fail(err)
+	}
+	err = cmd.run(s, flags.Args()[1:])
+	s.close()
+	if err != nil {
+		fail(err)
+	}
+}
+
+func usage(flags *flag.FlagSet) {
+	w := flags.Output()
+	fmt.Fprintf(w, "usage: rustplus [flags] <command> [arguments]\n\ncommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-54s %s\n", c.usage, c.summary)
+	}
+	fmt.Fprintf(w, "\nflags:\n")
+	flags.PrintDefaults()
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "rustplus: %s\n", err)
+	os.Exit(1)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Prints results either as text for people or as JSON for scripts, one document per line.
+type output struct {
+	w io.Writer
+	// Guards json, which the shell switches while broadcasts are printed from the read loop.
+	lock sync.Mutex
+	json bool
+}
+
+func (o *output) setJSON(on bool) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.json = on
+}
+
+func (o *output) jsonOn() bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return o.json
+}
+
+// Prints a protobuf message, using text to describe it unless JSON output is on.
+func (o *output) message(m proto.Message, text func(w io.Writer)) error {
+	if !o.jsonOn() {
+		text(o.w)
+		return nil
+	}
+	data, err := compactJSON(m)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(o.w, "%s\n", data)
+	return err
+}
+
+// protojson adds random whitespace to discourage byte comparisons, so settle on the compact form.
+func compactJSON(m proto.Message) ([]byte, error) {
+	data, err := protojson.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return nil, err
+	}
+	return compact.Bytes(), nil
+}
+
+// Prints any other value, using text to describe it unless JSON output is on.
+func (o *output) value(v interface{}, text func(w io.Writer)) error {
+	if !o.jsonOn() {
+		text(o.w)
+		return nil
+	}
+	return json.NewEncoder(o.w).Encode(v)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fishykins/gorustplus/pkg/config"
+	"github.com/fishykins/gorustplus/pkg/rustplus"
+)
+
+// A connected client with a read loop, so commands can wait on responses.
+type session struct {
+	client  *rustplus.Client
+	server  *config.Server
+	devices []*rustplus.Device
+	out     *output
+	timeout time.Duration
+	closed  chan error
+
+	// True in the shell, where commands should not block waiting for Ctrl-C.
+	interactive bool
+	lock        sync.Mutex
+	onBroadcast func(b *rustplus.AppBroadcast)
+}
+
+func connect(server *config.Server, out *output, timeout time.Duration) (*session, error) {
+	data := server.ConnectionData()
+	c := rustplus.NewClient(&data)
+	c.VerifyTokens(true, timeout)
+	if err := c.Connect(); err != nil {
+		return nil, fmt.Errorf("connecting to %s:%d: %s", server.Ip, server.Port, err)
+	}
+	s := &session{
+		client:  c,
+		server:  server,
+		devices: server.NewDevices(),
+		out:     out,
+		timeout: timeout,
+		closed:  make(chan error, 1),
+	}
+	go s.readLoop()
+	return s, nil
+}
+
+func (s *session) readLoop() {
+	for {
+		message, err := s.client.Read()
+		if err != nil {
+			s.closed <- err
+			return
+		}
+		if message == nil {
+			continue
+		}
+		s.lock.Lock()
+		watch := s.onBroadcast
+		s.lock.Unlock()
+		if watch != nil && message.Broadcast != nil {
+			watch(message.Broadcast)
+		}
+		s.client.HandleMessage(message)
+	}
+}
+
+// Calls f with every broadcast as it arrives, including those for unregistered devices.
+func (s *session) watch(f func(b *rustplus.AppBroadcast)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.onBroadcast = f
+}
+
+func (s *session) close() {
+	s.client.Disconnect()
+}
+
+// Sends a request and waits for the response, turning server errors into Go errors.
+func (s *session) call(req *rustplus.AppRequest, err error) (*rustplus.AppResponse, error) {
+	if err != nil {
+		return nil, err
+	}
+	responses := make(chan *rustplus.AppResponse, 1)
+	cb := rustplus.NewPrimitiveCb(func(r *rustplus.AppResponse) {
+		responses <- r
+	})
+	if err := s.client.Write(req, cb); err != nil {
+		return nil, err
+	}
+	select {
+	case r := <-responses:
+		if r.Error != nil {
+			return nil, fmt.Errorf("server error: %s", r.Error.GetError())
+		}
+		return r, nil
+	case err := <-s.closed:
+		s.closed <- err
+		return nil, fmt.Errorf("connection closed: %s", err)
+	case <-time.After(s.timeout):
+		return nil, errors.New("timed out waiting for the server")
+	}
+}
+
+func (s *session) info() (*rustplus.AppInfo, error) {
+	r, err := s.call(s.client.NewInfoRequest())
+	if err != nil {
+		return nil, err
+	}
+	return r.Info, nil
+}
+
+// Finds a configured device by id or name. Unknown ids are allowed, so unconfigured entities can still be used.
+func (s *session) device(arg string) (*rustplus.Device, error) {
+	id, err := strconv.ParseUint(arg, 10, 32)
+	for _, d := range s.devices {
+		if (err == nil && d.GetId() == uint32(id)) || strings.EqualFold(d.Name, arg) {
+			return d, nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no device named %q", arg)
+	}
+	return rustplus.NewDevice(uint32(id), ""), nil
+}
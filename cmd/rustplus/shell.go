@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fishykins/gorustplus/pkg/rustplus"
+	"golang.org/x/term"
+)
+
+// Commands that only make sense inside the shell.
+var shellCommands = map[string]string{
+	"help":    "list commands",
+	"devices": "list configured devices",
+	"raw":     `send a request written as JSON, e.g. raw {"getTime": {}}`,
+	"json":    "json on|off, switch JSON output",
+	"exit":    "leave the shell",
+}
+
+// Reads lines from a terminal with completion and history, or plain lines when input is piped.
+type lineReader interface {
+	ReadLine() (string, error)
+}
+
+type plainReader struct {
+	scanner *bufio.Scanner
+}
+
+func (r *plainReader) ReadLine() (string, error) {
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+type stdio struct {
+	io.Reader
+	io.Writer
+}
+
+func runShell(s *session, args []string) error {
+	s.interactive = true
+	var lines lineReader
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		defer term.Restore(fd, state)
+		t := term.NewTerminal(stdio{os.Stdin, os.Stdout}, "rustplus> ")
+		if width, height, err := term.GetSize(fd); err == nil {
+			t.SetSize(width, height)
+		}
+		t.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+			if key != '\t' {
+				return "", 0, false
+			}
+			return s.complete(t, line, pos)
+		}
+		// Writes through the terminal keep the prompt intact while broadcasts arrive.
+		s.out.w = t
+		lines = t
+	} else {
+		lines = &plainReader{bufio.NewScanner(os.Stdin)}
+	}
+
+	// The server only sends changes for entities we have asked about.
+	for _, d := range s.devices {
+		if req, err := s.client.NewDeviceGetRequest(d); err == nil {
+			s.client.Write(req, nil)
+		}
+	}
+	s.watch(s.printBroadcast)
+	defer s.watch(nil)
+
+	fmt.Fprintf(s.out.w, "connected to %s:%d, type help for commands\n", s.server.Ip, s.server.Port)
+	for {
+		line, err := lines.ReadLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		args := splitArgs(line)
+		if len(args) == 0 {
+			continue
+		}
+		if args[0] == "exit" || args[0] == "quit" {
+			return nil
+		}
+		if err := s.exec(args, line); err != nil {
+			fmt.Fprintf(s.out.w, "error: %s\n", err)
+		}
+		select {
+		case err := <-s.closed:
+			return fmt.Errorf("connection closed: %s", err)
+		default:
+		}
+	}
+}
+
+func (s *session) exec(args []string, line string) error {
+	switch args[0] {
+	case "help":
+		s.help()
+		return nil
+	case "devices":
+		s.listDevices()
+		return nil
+	case "raw":
+		// Take the JSON as typed, quotes and all.
+		return s.raw(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "raw")))
+	case "json":
+		if len(args) != 2 || (args[1] != "on" && args[1] != "off") {
+			return errors.New("usage: json on|off")
+		}
+		s.out.setJSON(args[1] == "on")
+		return nil
+	case "shell":
+		return errors.New("already in the shell")
+	}
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+	return cmd.run(s, args[1:])
+}
+
+func (s *session) help() {
+	w := s.out.w
+	for _, c := range commands {
+		if c.name != "shell" {
+			fmt.Fprintf(w, "  %-54s %s\n", c.usage, c.summary)
+		}
+	}
+	for _, name := range sortedKeys(shellCommands) {
+		fmt.Fprintf(w, "  %-54s %s\n", name, shellCommands[name])
+	}
+}
+
+func (s *session) listDevices() {
+	type row struct {
+		Id    uint32 `json:"id"`
+		Name  string `json:"name"`
+		Group string `json:"group,omitempty"`
+		Type  string `json:"type,omitempty"`
+	}
+	rows := make([]row, 0, len(s.devices))
+	for _, d := range s.devices {
+		r := row{Id: d.GetId(), Name: d.Name, Group: d.Group}
+		if d.HasType() {
+			r.Type = d.GetType().String()
+		}
+		rows = append(rows, r)
+	}
+	s.out.value(rows, func(w io.Writer) {
+		for _, r := range rows {
+			fmt.Fprintf(w, "%-10d %-14s %-24s %s\n", r.Id, r.Type, r.Name, r.Group)
+		}
+	})
+}
+
+// Sends an AppRequest written as protojson. The seq is always filled in, and the player token when it is missing.
+func (s *session) raw(literal string) error {
+	if literal == "" {
+		return errors.New(`usage: raw {"getInfo": {}}`)
+	}
+	r, err := s.call(s.client.NewRawRequest([]byte(literal)))
+	if err != nil {
+		return err
+	}
+	data, err := compactJSON(r)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out.w, "%s\n", data)
+	return nil
+}
+
+// Prints a broadcast from the read loop. The JSON flag is read once, so the shell switching it meanwhile is harmless.
+func (s *session) printBroadcast(b *rustplus.AppBroadcast) {
+	s.out.message(b, func(w io.Writer) {
+		at := time.Now().Format("15:04:05")
+		switch {
+		case b.EntityChanged != nil:
+			id := b.EntityChanged.GetEntityId()
+			name := strconv.FormatUint(uint64(id), 10)
+			for _, d := range s.devices {
+				if d.GetId() == id {
+					name = fmt.Sprintf("%s (%d)", d.Name, id)
+				}
+			}
+			payload := b.EntityChanged.GetPayload()
+			fmt.Fprintf(w, "%s entity %s: value %t, %d items\n", at, name, payload.GetValue(), len(payload.GetItems()))
+		case b.TeamMessage != nil:
+			writeChat(w, b.TeamMessage.GetMessage())
+		case b.TeamChanged != nil:
+			fmt.Fprintf(w, "%s team changed, %d members\n", at, len(b.TeamChanged.GetTeamInfo().GetMembers()))
+		default:
+			data, _ := compactJSON(b)
+			fmt.Fprintf(w, "%s broadcast %s\n", at, data)
+		}
+	})
+}
+
+// Completes the word under the cursor. Several matches are listed and the common prefix filled in.
+func (s *session) complete(w io.Writer, line string, pos int) (string, int, bool) {
+	prefix := line[:pos]
+	start := wordStart(prefix)
+	current := prefix[start:]
+	previous := splitArgs(prefix[:start])
+
+	candidates := s.candidates(previous)
+	matches := make([]string, 0)
+	for _, c := range candidates {
+		lower, word := strings.ToLower(c), strings.ToLower(current)
+		if strings.HasPrefix(lower, word) || strings.HasPrefix(lower, `"`+word) {
+			matches = append(matches, c)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return line, pos, true
+	case 1:
+		completed := prefix[:start] + matches[0] + " "
+		return completed + line[pos:], len(completed), true
+	}
+	common := commonPrefix(matches)
+	if len(common) > len(current) {
+		completed := prefix[:start] + common
+		return completed + line[pos:], len(completed), true
+	}
+	fmt.Fprintf(w, "%s\n", strings.Join(matches, "  "))
+	return line, pos, true
+}
+
+// What can follow the words already typed.
+func (s *session) candidates(previous []string) []string {
+	if len(previous) == 0 {
+		names := sortedKeys(shellCommands)
+		for _, c := range commands {
+			if c.name != "shell" {
+				names = append(names, c.name)
+			}
+		}
+		sort.Strings(names)
+		return names
+	}
+	switch previous[0] {
+	case "chat":
+		if len(previous) == 1 {
+			return []string{"send", "tail"}
+		}
+	case "camera":
+		if len(previous) == 1 {
+			return []string{"snapshot"}
+		}
+	case "json":
+		if len(previous) == 1 {
+			return []string{"on", "off"}
+		}
+	case "entity":
+		switch len(previous) {
+		case 1:
+			return []string{"get", "set"}
+		case 2:
+			return s.deviceNames()
+		case 3:
+			if previous[1] == "set" {
+				return []string{"on", "off"}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *session) deviceNames() []string {
+	names := make([]string, 0, len(s.devices)*2)
+	for _, d := range s.devices {
+		names = append(names, strconv.FormatUint(uint64(d.GetId()), 10))
+		if d.Name == "" {
+			continue
+		}
+		if strings.ContainsAny(d.Name, " \t") {
+			names = append(names, strconv.Quote(d.Name))
+		} else {
+			names = append(names, d.Name)
+		}
+	}
+	return names
+}
+
+// Splits a line into words, keeping double quoted text together.
+func splitArgs(line string) []string {
+	args := make([]string, 0)
+	var word strings.Builder
+	inWord, quoted := false, false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inWord = true
+		case (r == ' ' || r == '\t') && !quoted:
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args
+}
+
+// Where the word under the cursor starts, ignoring spaces inside quotes.
+func wordStart(prefix string) int {
+	start, quoted := 0, false
+	for i, r := range prefix {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case (r == ' ' || r == '\t') && !quoted:
+			start = i + 1
+		}
+	}
+	return start
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(strings.ToLower(w), strings.ToLower(prefix)) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
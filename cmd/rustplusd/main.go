@@ -0,0 +1,83 @@
+// Command rustplusd keeps bots connected to every server in a config and runs plugins against them.
+//
+//	rustplusd [-config rustplus.json] [-plugin "python3 door.py"]... [-v]
+//
+// Each -plugin is a program started with the daemon, see daemon.ProcessPlugin for the protocol it speaks.
+// Teams writing plugins in Go can build their own binary around package daemon instead.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/fishykins/gorustplus/pkg/config"
+	"github.com/fishykins/gorustplus/pkg/daemon"
+)
+
+// Collects repeated -plugin flags.
+type pluginFlags []string
+
+func (p *pluginFlags) String() string {
+	return strings.Join(*p, ", ")
+}
+
+func (p *pluginFlags) Set(v string) error {
+	if len(strings.Fields(v)) == 0 {
+		return fmt.Errorf("empty plugin command")
+	}
+	*p = append(*p, v)
+	return nil
+}
+
+// Logs every event, for seeing what plugins will receive.
+type eventLogger struct{}
+
+func (eventLogger) Name() string {
+	return "log"
+}
+
+func (eventLogger) HandleEvent(b *daemon.Bot, e *daemon.Event) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("%s: %s event: %s", b.Name(), e.Type, err)
+		return
+	}
+	log.Printf("%s", data)
+}
+
+func main() {
+	configPath := flag.String("config", config.DefaultPath(), "config file with servers, player tokens and devices")
+	verbose := flag.Bool("v", false, "log every event")
+	var plugins pluginFlags
+	flag.Var(&plugins, "plugin", "command to run as a plugin, may be repeated")
+	flag.Parse()
+
+	conf, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("rustplusd: %s", err)
+	}
+
+	d := daemon.New(conf)
+	if *verbose {
+		d.Use(eventLogger{})
+	}
+	for _, p := range plugins {
+		fields := strings.Fields(p)
+		d.Use(daemon.NewProcessPlugin(filepath.Base(fields[0]), fields[0], fields[1:]...))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	log.Printf("rustplusd: running %d servers with %d plugins", len(d.Bots()), len(plugins))
+	if err := d.Run(ctx); err != nil && err != context.Canceled {
+		log.Fatalf("rustplusd: %s", err)
+	}
+}
@@ -4,5 +4,6 @@ go 1.16
 
 require (
 	github.com/gorilla/websocket v1.4.2
+	golang.org/x/term v0.0.0-20220526004731-065cf7ba2467
 	google.golang.org/protobuf v1.27.1
 )
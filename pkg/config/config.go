@@ -0,0 +1,359 @@
+// Package config loads servers, player tokens and devices from a JSON file and the environment.
+//
+// A config file looks like this:
+//
+//	{
+//	  "servers": [
+//	    {
+//	      "name": "main",
+//	      "ip": "1.2.3.4",
+//	      "port": 28082,
+//	      "useProxy": false,
+//	      "proxyUrl": "wss://relay.example.com/game",
+//	      "tokens": [{"name": "fishy", "steamId": 76561198000000000, "token": -123456789}],
+//	      "devices": [{"id": 1234567, "name": "Front door", "group": "doors", "type": "Switch"}]
+//	    }
+//	  ]
+//	}
+//
+// Environment variables override the file, see ApplyEnv.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fishykins/gorustplus/pkg/rustplus"
+)
+
+type Config struct {
+	Servers []*Server `json:"servers"`
+}
+
+type Server struct {
+	Name     string `json:"name"`
+	Ip       string `json:"ip"`
+	Port     uint64 `json:"port"`
+	UseProxy bool   `json:"useProxy,omitempty"`
+	// Companion proxy or relay to use instead of Facepunch's. Optional.
+	ProxyUrl string    `json:"proxyUrl,omitempty"`
+	Tokens   []*Token  `json:"tokens"`
+	Devices  []*Device `json:"devices,omitempty"`
+}
+
+type Token struct {
+	Name    string `json:"name,omitempty"`
+	SteamId uint64 `json:"steamId"`
+	Token   int32  `json:"token"`
+}
+
+type Device struct {
+	Id    uint32 `json:"id"`
+	Name  string `json:"name"`
+	Group string `json:"group,omitempty"`
+	// Steam id of the player that paired the device. Optional.
+	Owner uint64 `json:"owner,omitempty"`
+	// One of the AppEntityType names: Switch, Alarm or StorageMonitor. Optional.
+	Type string `json:"type,omitempty"`
+}
+
+// A problem with a single entry in the config.
+type ValidationError struct {
+	// Where the problem is, e.g. "servers[0].tokens[1].steamId".
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Every problem found in a config.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// The config file used when none is given: RUSTPLUS_CONFIG if set, otherwise rustplus.json in the working directory.
+func DefaultPath() string {
+	if path, ok := os.LookupEnv("RUSTPLUS_CONFIG"); ok {
+		return path
+	}
+	return "rustplus.json"
+}
+
+// Loads a config file, applies any environment overrides and validates the result.
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	c, err := decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	if err := c.ApplyEnv(); err != nil {
+		return nil, err
+	}
+	return c, c.Validate()
+}
+
+// Parses and validates a config. The environment is not consulted.
+func Parse(r io.Reader) (*Config, error) {
+	c, err := decode(r)
+	if err != nil {
+		return nil, err
+	}
+	return c, c.Validate()
+}
+
+func decode(r io.Reader) (*Config, error) {
+	c := &Config{}
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Checks every entry, returning ValidationErrors describing anything wrong.
+func (c *Config) Validate() error {
+	errs := make(ValidationErrors, 0)
+	add := func(path, format string, args ...interface{}) {
+		errs = append(errs, &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	if len(c.Servers) == 0 {
+		add("servers", "no servers configured")
+	}
+	names := make(map[string]int)
+	for i, s := range c.Servers {
+		path := fmt.Sprintf("servers[%d]", i)
+		if s == nil {
+			add(path, "is empty")
+			continue
+		}
+		if s.Name != "" {
+			if j, ok := names[s.Name]; ok {
+				add(path+".name", "%q is already used by servers[%d]", s.Name, j)
+			}
+			names[s.Name] = i
+		}
+		if s.Ip == "" {
+			add(path+".ip", "is required")
+		}
+		if s.Port == 0 || s.Port > 65535 {
+			add(path+".port", "must be between 1 and 65535")
+		}
+		if s.ProxyUrl != "" {
+			if u, err := url.Parse(s.ProxyUrl); err != nil || (u.Scheme != "ws" && u.Scheme != "wss") {
+				add(path+".proxyUrl", "must be a ws:// or wss:// url")
+			}
+		}
+		if len(s.Tokens) == 0 {
+			add(path+".tokens", "at least one player token is required")
+		}
+		for j, t := range s.Tokens {
+			tokenPath := fmt.Sprintf("%s.tokens[%d]", path, j)
+			if t == nil {
+				add(tokenPath, "is empty")
+				continue
+			}
+			if t.SteamId == 0 {
+				add(tokenPath+".steamId", "is required")
+			}
+			if t.Token == 0 {
+				add(tokenPath+".token", "is required")
+			}
+		}
+		ids := make(map[uint32]int)
+		for j, d := range s.Devices {
+			devicePath := fmt.Sprintf("%s.devices[%d]", path, j)
+			if d == nil {
+				add(devicePath, "is empty")
+				continue
+			}
+			if d.Id == 0 {
+				add(devicePath+".id", "is required")
+			} else if k, ok := ids[d.Id]; ok {
+				add(devicePath+".id", "%d is already used by devices[%d]", d.Id, k)
+			}
+			ids[d.Id] = j
+			if d.Type != "" {
+				if _, ok := rustplus.AppEntityType_value[d.Type]; !ok {
+					add(devicePath+".type", "unknown type %q, expected Switch, Alarm or StorageMonitor", d.Type)
+				}
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Applies overrides from the environment:
+//
+//	RUSTPLUS_SERVER    name of the server to override, defaults to the first (created if there are none)
+//	RUSTPLUS_IP        server address
+//	RUSTPLUS_PORT      server port
+//	RUSTPLUS_PROXY     true to connect through the Facepunch proxy
+//	RUSTPLUS_STEAM_ID  steam id of a player token, used before any from the file
+//	RUSTPLUS_TOKEN     the player token for RUSTPLUS_STEAM_ID
+func (c *Config) ApplyEnv() error {
+	vars := []string{"RUSTPLUS_IP", "RUSTPLUS_PORT", "RUSTPLUS_PROXY", "RUSTPLUS_STEAM_ID", "RUSTPLUS_TOKEN"}
+	set := false
+	for _, v := range vars {
+		if _, ok := os.LookupEnv(v); ok {
+			set = true
+		}
+	}
+	if !set {
+		return nil
+	}
+
+	name := os.Getenv("RUSTPLUS_SERVER")
+	s, err := c.Server(name)
+	if err != nil {
+		s = &Server{Name: name}
+		c.Servers = append(c.Servers, s)
+	}
+
+	errs := make(ValidationErrors, 0)
+	if ip, ok := os.LookupEnv("RUSTPLUS_IP"); ok {
+		s.Ip = ip
+	}
+	if port, ok := os.LookupEnv("RUSTPLUS_PORT"); ok {
+		p, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			errs = append(errs, &ValidationError{"RUSTPLUS_PORT", fmt.Sprintf("invalid port %q", port)})
+		}
+		s.Port = p
+	}
+	if proxy, ok := os.LookupEnv("RUSTPLUS_PROXY"); ok {
+		p, err := strconv.ParseBool(proxy)
+		if err != nil {
+			errs = append(errs, &ValidationError{"RUSTPLUS_PROXY", fmt.Sprintf("invalid bool %q", proxy)})
+		}
+		s.UseProxy = p
+	}
+	steamId, hasSteamId := os.LookupEnv("RUSTPLUS_STEAM_ID")
+	token, hasToken := os.LookupEnv("RUSTPLUS_TOKEN")
+	if hasSteamId != hasToken {
+		errs = append(errs, &ValidationError{"RUSTPLUS_STEAM_ID", "RUSTPLUS_STEAM_ID and RUSTPLUS_TOKEN must be set together"})
+	} else if hasSteamId {
+		id, err := strconv.ParseUint(steamId, 10, 64)
+		if err != nil {
+			errs = append(errs, &ValidationError{"RUSTPLUS_STEAM_ID", fmt.Sprintf("invalid steam id %q", steamId)})
+		}
+		t, err := strconv.ParseInt(token, 10, 32)
+		if err != nil {
+			errs = append(errs, &ValidationError{"RUSTPLUS_TOKEN", fmt.Sprintf("invalid token %q", token)})
+		}
+		s.Tokens = append([]*Token{{SteamId: id, Token: int32(t)}}, s.Tokens...)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Finds a server by name. An empty name returns the first server.
+func (c *Config) Server(name string) (*Server, error) {
+	for _, s := range c.Servers {
+		if s != nil && (name == "" || s.Name == name) {
+			return s, nil
+		}
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no servers configured")
+	}
+	return nil, fmt.Errorf("server not found: %s", name)
+}
+
+// Writes the config as indented JSON.
+func (c *Config) Write(w io.Writer) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, bytes.NewReader(append(data, '\n')))
+	return err
+}
+
+// Writes the config to a file. The file holds player tokens, so it is only readable by the owner.
+func (c *Config) Save(path string) error {
+	var buf bytes.Buffer
+	if err := c.Write(&buf); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0600)
+}
+
+// Connection data for the server, with all of its tokens.
+func (s *Server) ConnectionData() rustplus.ConnectionData {
+	data := rustplus.NewConnectionData(s.Ip, s.Port, s.UseProxy)
+	data.SetProxyURL(s.ProxyUrl)
+	for _, t := range s.Tokens {
+		data.AddToken(rustplus.PlayerToken{Name: t.Name, SteamId: t.SteamId, Token: t.Token})
+	}
+	return data
+}
+
+// Creates a device for every configured device, ready to be added to a client.
+func (s *Server) NewDevices() []*rustplus.Device {
+	devices := make([]*rustplus.Device, 0, len(s.Devices))
+	for _, d := range s.Devices {
+		device := rustplus.NewDevice(d.Id, d.Name)
+		device.Group = d.Group
+		device.Owner = d.Owner
+		if t, ok := rustplus.AppEntityType_value[d.Type]; ok {
+			device.SetType(rustplus.AppEntityType(t))
+		}
+		devices = append(devices, device)
+	}
+	return devices
+}
+
+// Captures a client's current connection and devices, so it can be saved.
+func FromClient(name string, c *rustplus.Client) *Server {
+	data := c.GetConnectionData()
+	s := &Server{
+		Name:     name,
+		Ip:       data.GetIp(),
+		Port:     data.GetPort(),
+		UseProxy: data.UsesProxy(),
+		Tokens:   make([]*Token, 0, len(data.Tokens)),
+		Devices:  make([]*Device, 0),
+	}
+	if proxy := data.ProxyURL(); proxy != rustplus.DefaultProxyURL {
+		s.ProxyUrl = proxy
+	}
+	for _, t := range data.Tokens {
+		s.Tokens = append(s.Tokens, &Token{Name: t.Name, SteamId: t.SteamId, Token: t.Token})
+	}
+	for _, d := range c.GetDevices() {
+		device := &Device{Id: d.GetId(), Name: d.Name, Group: d.Group, Owner: d.Owner}
+		if d.HasType() {
+			device.Type = d.GetType().String()
+		}
+		s.Devices = append(s.Devices, device)
+	}
+	sort.Slice(s.Devices, func(i, j int) bool {
+		return s.Devices[i].Id < s.Devices[j].Id
+	})
+	return s
+}
@@ -0,0 +1,210 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const validConfig = `{
+  "servers": [
+    {
+      "name": "main",
+      "ip": "1.2.3.4",
+      "port": 28082,
+      "proxyUrl": "wss://relay.example.com/game",
+      "tokens": [{"name": "fishy", "steamId": 76561198000000000, "token": -123456789}],
+      "devices": [{"id": 1234567, "name": "Front door", "group": "doors", "type": "Switch"}]
+    }
+  ]
+}`
+
+func TestParseValid(t *testing.T) {
+	c, err := Parse(strings.NewReader(validConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Config{Servers: []*Server{{
+		Name:     "main",
+		Ip:       "1.2.3.4",
+		Port:     28082,
+		ProxyUrl: "wss://relay.example.com/game",
+		Tokens:   []*Token{{Name: "fishy", SteamId: 76561198000000000, Token: -123456789}},
+		Devices:  []*Device{{Id: 1234567, Name: "Front door", Group: "doors", Type: "Switch"}},
+	}}}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("got %+v, want %+v", c.Servers[0], want.Servers[0])
+	}
+	data := c.Servers[0].ConnectionData()
+	if got := data.URL(); got != "ws://1.2.3.4:28082" {
+		t.Errorf("got url %s, want ws://1.2.3.4:28082", got)
+	}
+	if devices := c.Servers[0].NewDevices(); len(devices) != 1 || !devices[0].HasType() {
+		t.Errorf("got devices %+v, want one typed device", devices)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{"no servers", `{"servers": []}`, []string{"servers"}},
+		{"empty server", `{"servers": [null]}`, []string{"servers[0]"}},
+		{
+			"missing fields",
+			`{"servers": [{"name": "main"}]}`,
+			[]string{"servers[0].ip", "servers[0].port", "servers[0].tokens"},
+		},
+		{
+			"bad values",
+			`{"servers": [{"ip": "1.2.3.4", "port": 70000, "proxyUrl": "https://relay.example.com",
+				"tokens": [{"steamId": 0, "token": 0}, null],
+				"devices": [{"id": 0}, {"id": 5, "type": "Door"}, {"id": 5}, null]}]}`,
+			[]string{
+				"servers[0].port", "servers[0].proxyUrl",
+				"servers[0].tokens[0].steamId", "servers[0].tokens[0].token", "servers[0].tokens[1]",
+				"servers[0].devices[0].id", "servers[0].devices[1].type", "servers[0].devices[2].id", "servers[0].devices[3]",
+			},
+		},
+		{
+			"duplicate names",
+			`{"servers": [
+				{"name": "main", "ip": "1.2.3.4", "port": 1, "tokens": [{"steamId": 1, "token": 1}]},
+				{"name": "main", "ip": "1.2.3.4", "port": 2, "tokens": [{"steamId": 1, "token": 1}]}]}`,
+			[]string{"servers[1].name"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(test.config))
+			errs, ok := err.(ValidationErrors)
+			if !ok {
+				t.Fatalf("got error %v, want ValidationErrors", err)
+			}
+			paths := make([]string, len(errs))
+			for i, e := range errs {
+				paths[i] = e.Path
+			}
+			sort.Strings(paths)
+			sort.Strings(test.want)
+			if !reflect.DeepEqual(paths, test.want) {
+				t.Errorf("got problems at %v, want %v\n%s", paths, test.want, err)
+			}
+		})
+	}
+}
+
+func TestParseUnknownField(t *testing.T) {
+	_, err := Parse(strings.NewReader(`{"servers": [], "server": {}}`))
+	if err == nil || !strings.Contains(err.Error(), "unknown field") {
+		t.Errorf("got error %v, want an unknown field error", err)
+	}
+}
+
+var envVars = []string{"RUSTPLUS_SERVER", "RUSTPLUS_IP", "RUSTPLUS_PORT", "RUSTPLUS_PROXY", "RUSTPLUS_STEAM_ID", "RUSTPLUS_TOKEN"}
+
+// Sets the given variables and clears the other overrides until the test ends.
+func setenv(t *testing.T, vars map[string]string) {
+	for _, k := range envVars {
+		old, ok := os.LookupEnv(k)
+		if v, set := vars[k]; set {
+			os.Setenv(k, v)
+		} else {
+			os.Unsetenv(k)
+		}
+		k := k
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(k, old)
+			} else {
+				os.Unsetenv(k)
+			}
+		})
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	setenv(t, map[string]string{
+		"RUSTPLUS_SERVER":   "main",
+		"RUSTPLUS_PORT":     "28083",
+		"RUSTPLUS_PROXY":    "true",
+		"RUSTPLUS_STEAM_ID": "76561198000000001",
+		"RUSTPLUS_TOKEN":    "42",
+	})
+	c, err := Parse(strings.NewReader(validConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ApplyEnv(); err != nil {
+		t.Fatal(err)
+	}
+	s := c.Servers[0]
+	if s.Ip != "1.2.3.4" || s.Port != 28083 || !s.UseProxy {
+		t.Errorf("got %s:%d proxy %t, want 1.2.3.4:28083 proxy true", s.Ip, s.Port, s.UseProxy)
+	}
+	if len(s.Tokens) != 2 || s.Tokens[0].SteamId != 76561198000000001 || s.Tokens[0].Token != 42 {
+		t.Errorf("got tokens %+v, want the environment's first", s.Tokens)
+	}
+}
+
+func TestApplyEnvErrors(t *testing.T) {
+	setenv(t, map[string]string{"RUSTPLUS_PORT": "port", "RUSTPLUS_STEAM_ID": "1"})
+	c := &Config{}
+	errs, ok := c.ApplyEnv().(ValidationErrors)
+	if !ok || len(errs) != 2 || errs[0].Path != "RUSTPLUS_PORT" || errs[1].Path != "RUSTPLUS_STEAM_ID" {
+		t.Errorf("got %v, want errors for RUSTPLUS_PORT and RUSTPLUS_STEAM_ID", errs)
+	}
+	if len(c.Servers) != 1 {
+		t.Errorf("got %d servers, want one created from the environment", len(c.Servers))
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	c, err := Parse(strings.NewReader(validConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setenv(t, nil)
+	path := filepath.Join(t.TempDir(), "rustplus.json")
+	if err := c.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("saved with mode %o, want 600", perm)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, c) {
+		t.Errorf("loaded %+v, want %+v", loaded.Servers[0], c.Servers[0])
+	}
+}
+
+func TestDefaultPath(t *testing.T) {
+	old, ok := os.LookupEnv("RUSTPLUS_CONFIG")
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv("RUSTPLUS_CONFIG", old)
+		} else {
+			os.Unsetenv("RUSTPLUS_CONFIG")
+		}
+	})
+	os.Unsetenv("RUSTPLUS_CONFIG")
+	if got := DefaultPath(); got != "rustplus.json" {
+		t.Errorf("got %s, want rustplus.json", got)
+	}
+	os.Setenv("RUSTPLUS_CONFIG", "/etc/rustplus.json")
+	if got := DefaultPath(); got != "/etc/rustplus.json" {
+		t.Errorf("got %s, want /etc/rustplus.json", got)
+	}
+}
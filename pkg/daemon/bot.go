@@ -0,0 +1,198 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fishykins/gorustplus/pkg/config"
+	"github.com/fishykins/gorustplus/pkg/rustplus"
+)
+
+const (
+	// Reconnect delays start here and double after every failed attempt.
+	MinReconnectDelay = time.Second
+	MaxReconnectDelay = time.Minute
+)
+
+// Keeps a connection to one server alive and turns what it hears into events.
+type Bot struct {
+	server  *config.Server
+	client  *rustplus.Client
+	devices []*rustplus.Device
+	chat    *rustplus.ChatSender
+	events  chan *Event
+	lock    sync.Mutex
+	online  bool
+}
+
+func newBot(server *config.Server) *Bot {
+	data := server.ConnectionData()
+	b := &Bot{
+		server:  server,
+		client:  rustplus.NewStandaloneClient(&data),
+		devices: server.NewDevices(),
+		events:  make(chan *Event, 256),
+	}
+	b.client.VerifyTokens(true, 0)
+	for _, d := range b.devices {
+		d := d
+		d.AddBroadcastEvent(func(_ *rustplus.Device, p *rustplus.AppEntityPayload) {
+			b.emit(&Event{Type: EventEntity, Device: d, Entity: p})
+		})
+		b.client.AddDevice(d)
+	}
+	b.chat = rustplus.NewChatSender(b.client, "")
+	return b
+}
+
+// The server's name from the config.
+func (b *Bot) Name() string {
+	return b.server.Name
+}
+
+func (b *Bot) Server() *config.Server {
+	return b.server
+}
+
+// The underlying client, for anything the bot does not wrap.
+func (b *Bot) Client() *rustplus.Client {
+	return b.client
+}
+
+func (b *Bot) Connected() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.online
+}
+
+// Queues a team chat message. Long messages are split and paced out.
+func (b *Bot) Say(message string) error {
+	return b.chat.Send(message)
+}
+
+// Finds a configured device by id or name.
+func (b *Bot) Device(idOrName string) (*rustplus.Device, error) {
+	id, err := strconv.ParseUint(idOrName, 10, 32)
+	for _, d := range b.devices {
+		if (err == nil && d.GetId() == uint32(id)) || strings.EqualFold(d.Name, idOrName) {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: no device %q", b.Name(), idOrName)
+}
+
+// Sends a request and waits for the response.
+func (b *Bot) Call(req *rustplus.AppRequest, timeout time.Duration) (*rustplus.AppResponse, error) {
+	responses := make(chan *rustplus.AppResponse, 1)
+	cb := rustplus.NewPrimitiveCb(func(r *rustplus.AppResponse) {
+		responses <- r
+	})
+	if err := b.client.Write(req, cb); err != nil {
+		return nil, err
+	}
+	select {
+	case r := <-responses:
+		if r.Error != nil {
+			return r, fmt.Errorf("server error: %s", r.Error.GetError())
+		}
+		return r, nil
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("%s: timed out waiting for the server", b.Name())
+	}
+}
+
+// Connects and reads until the context is cancelled, reconnecting whenever the connection drops.
+func (b *Bot) run(ctx context.Context, logf func(format string, args ...interface{})) {
+	chat := b.client.SubscribeChat(64, rustplus.DropOldest)
+	team := b.client.SubscribeTeam(16, rustplus.DropOldest)
+	defer chat.Close()
+	defer team.Close()
+	go func() {
+		for {
+			select {
+			case m, ok := <-chat.C:
+				if !ok {
+					return
+				}
+				b.emit(&Event{Type: EventChat, Chat: m})
+			case t, ok := <-team.C:
+				if !ok {
+					return
+				}
+				b.emit(&Event{Type: EventTeam, Team: t.GetTeamInfo()})
+			}
+		}
+	}()
+	b.chat.Start()
+	defer b.chat.Stop()
+
+	delay := MinReconnectDelay
+	for {
+		if err := b.client.Connect(); err != nil {
+			logf("%s: connect failed, retrying in %s: %s", b.Name(), delay, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			if delay *= 2; delay > MaxReconnectDelay {
+				delay = MaxReconnectDelay
+			}
+			continue
+		}
+		delay = MinReconnectDelay
+		b.setOnline(true)
+		logf("%s: connected to %s", b.Name(), b.client.ServerName())
+		b.emit(&Event{Type: EventConnected})
+
+		// Closing the connection is the only way to interrupt a blocked read.
+		stop := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				b.client.Disconnect()
+			case <-stop:
+			}
+		}()
+		err := b.read()
+		close(stop)
+		b.setOnline(false)
+		if ctx.Err() != nil {
+			return
+		}
+		logf("%s: disconnected: %s", b.Name(), err)
+		b.emit(&Event{Type: EventDisconnected, Err: err})
+	}
+}
+
+func (b *Bot) read() error {
+	for {
+		message, err := b.client.Read()
+		if err != nil {
+			b.client.Disconnect()
+			return err
+		}
+		if message != nil {
+			b.client.HandleMessage(message)
+		}
+	}
+}
+
+func (b *Bot) setOnline(online bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.online = online
+}
+
+// Queues an event for the plugins. Events are dropped rather than holding up the connection if plugins fall behind.
+func (b *Bot) emit(e *Event) {
+	e.Server = b.Name()
+	select {
+	case b.events <- e:
+	default:
+	}
+}
@@ -0,0 +1,144 @@
+// Package daemon runs a bot for every server in a config, reconnecting as needed, and hands what
+// happens on each server to plugins.
+//
+// Plugins are either Go values implementing Plugin, for teams building their own binary:
+//
+//	d := daemon.New(conf)
+//	d.Use(myPlugin)
+//	d.Run(ctx)
+//
+// or separate programs speaking JSON over stdin and stdout, see ProcessPlugin.
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/fishykins/gorustplus/pkg/config"
+)
+
+// Adds behaviour to the daemon.
+type Plugin interface {
+	Name() string
+	// Called for every event on every server. Each server has its own goroutine, so a slow plugin only holds up
+	// events from the server it is handling.
+	HandleEvent(b *Bot, e *Event)
+}
+
+// Plugins that need setting up once the bots exist, e.g. to look up devices.
+type Starter interface {
+	Start(d *Daemon) error
+}
+
+type Daemon struct {
+	bots    []*Bot
+	plugins []Plugin
+	lock    sync.Mutex
+
+	// Where the daemon logs connections and plugin errors. Defaults to the standard logger.
+	Logger *log.Logger
+}
+
+// Creates a daemon with a bot for every server in the config.
+func New(c *config.Config) *Daemon {
+	d := &Daemon{}
+	for _, s := range c.Servers {
+		d.bots = append(d.bots, newBot(s))
+	}
+	return d
+}
+
+// Adds a plugin. Plugins should be added before Run.
+func (d *Daemon) Use(p Plugin) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.plugins = append(d.plugins, p)
+}
+
+func (d *Daemon) Bots() []*Bot {
+	return d.bots
+}
+
+// Finds a bot by server name. An empty name returns the first bot.
+func (d *Daemon) Bot(name string) (*Bot, error) {
+	for _, b := range d.bots {
+		if name == "" || b.Name() == name {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("no server named %q", name)
+}
+
+// Starts the plugins and runs every bot until the context is cancelled. Plugins implementing io.Closer are closed on the way out.
+func (d *Daemon) Run(ctx context.Context) error {
+	d.lock.Lock()
+	plugins := append([]Plugin(nil), d.plugins...)
+	d.lock.Unlock()
+
+	for _, p := range plugins {
+		if s, ok := p.(Starter); ok {
+			if err := s.Start(d); err != nil {
+				return fmt.Errorf("plugin %s: %s", p.Name(), err)
+			}
+		}
+	}
+	defer func() {
+		for _, p := range plugins {
+			if c, ok := p.(io.Closer); ok {
+				if err := c.Close(); err != nil {
+					d.logf("plugin %s: %s", p.Name(), err)
+				}
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for _, b := range d.bots {
+		b := b
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			b.run(ctx, d.logf)
+		}()
+		go func() {
+			defer wg.Done()
+			d.dispatch(ctx, b, plugins)
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (d *Daemon) dispatch(ctx context.Context, b *Bot, plugins []Plugin) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-b.events:
+			for _, p := range plugins {
+				d.handle(p, b, e)
+			}
+		}
+	}
+}
+
+// Calls a plugin, keeping a panic in one plugin from taking down the daemon.
+func (d *Daemon) handle(p Plugin, b *Bot, e *Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			d.logf("plugin %s panicked on %s event: %v", p.Name(), e.Type, r)
+		}
+	}()
+	p.HandleEvent(b, e)
+}
+
+func (d *Daemon) logf(format string, args ...interface{}) {
+	if d.Logger != nil {
+		d.Logger.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
@@ -0,0 +1,84 @@
+package daemon
+
+import (
+	"encoding/json"
+
+	"github.com/fishykins/gorustplus/pkg/rustplus"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+type EventType string
+
+const (
+	// The bot connected, or reconnected, to its server.
+	EventConnected EventType = "connected"
+	// The connection dropped. The bot reconnects on its own.
+	EventDisconnected EventType = "disconnected"
+	// A team chat message.
+	EventChat EventType = "chat"
+	// The team changed, e.g. someone joined, left or died.
+	EventTeam EventType = "team"
+	// A configured device changed state.
+	EventEntity EventType = "entity"
+)
+
+// Something that happened on a server. Only the field matching Type is set.
+type Event struct {
+	Type EventType
+	// Name of the server, as given in the config.
+	Server string
+
+	Chat   *rustplus.AppChatMessage
+	Team   *rustplus.AppTeamInfo
+	Device *rustplus.Device
+	Entity *rustplus.AppEntityPayload
+	// Why the bot disconnected.
+	Err error
+}
+
+// The JSON form of an event, as sent to process plugins. Protobuf messages use their protojson encoding.
+type wireEvent struct {
+	Type   EventType       `json:"type"`
+	Server string          `json:"server"`
+	Chat   json.RawMessage `json:"chat,omitempty"`
+	Team   json.RawMessage `json:"team,omitempty"`
+	Device *wireDevice     `json:"device,omitempty"`
+	Entity json.RawMessage `json:"entity,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+type wireDevice struct {
+	Id    uint32 `json:"id"`
+	Name  string `json:"name"`
+	Group string `json:"group,omitempty"`
+}
+
+func (e *Event) MarshalJSON() ([]byte, error) {
+	w := wireEvent{Type: e.Type, Server: e.Server}
+	var err error
+	if w.Chat, err = marshalProto(e.Chat); err != nil {
+		return nil, err
+	}
+	if w.Team, err = marshalProto(e.Team); err != nil {
+		return nil, err
+	}
+	if w.Entity, err = marshalProto(e.Entity); err != nil {
+		return nil, err
+	}
+	if e.Device != nil {
+		w.Device = &wireDevice{Id: e.Device.GetId(), Name: e.Device.Name, Group: e.Device.Group}
+	}
+	if e.Err != nil {
+		w.Error = e.Err.Error()
+	}
+	return json.Marshal(w)
+}
+
+// Encodes a message with protojson, or nothing at all for a nil message.
+func marshalProto(m proto.Message) (json.RawMessage, error) {
+	if m == nil || !m.ProtoReflect().IsValid() {
+		return nil, nil
+	}
+	return protojson.Marshal(m)
+}
@@ -0,0 +1,258 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/fishykins/gorustplus/pkg/rustplus"
+)
+
+// Default time a process plugin's requests wait for the server.
+const DefaultPluginTimeout = 10 * time.Second
+
+// A plugin run as a separate program, so behaviours can be written in any language.
+//
+// Every event is written to the program's stdin as one line of JSON:
+//
+//	{"type":"chat","server":"main","chat":{"steamId":"7656...","name":"fishy","message":"!door open","time":1650000000}}
+//
+// The program writes commands to stdout, one JSON object per line:
+//
+//	{"id":"1","server":"main","action":"say","message":"opening"}
+//	{"id":"2","server":"main","action":"set","device":"Front door","value":true}
+//	{"id":"3","server":"main","action":"request","request":{"getTime":{}}}
+//
+// An empty server means the first one. Commands with an id are answered on stdin with a "response" line carrying the
+// same id, plus the server's response for requests or an error. Stderr is passed through. If the program exits it is
+// started again after RestartDelay.
+type ProcessPlugin struct {
+	name    string
+	command string
+	args    []string
+	daemon  *Daemon
+	lock    sync.Mutex
+	stdin   io.WriteCloser
+	process *os.Process
+	exited  chan struct{}
+	quit    chan struct{}
+	once    sync.Once
+
+	// How long requests wait for the server.
+	Timeout time.Duration
+	// How long to wait before starting the program again after it exits.
+	RestartDelay time.Duration
+}
+
+type pluginCommand struct {
+	Id      string          `json:"id,omitempty"`
+	Server  string          `json:"server,omitempty"`
+	Action  string          `json:"action"`
+	Message string          `json:"message,omitempty"`
+	Device  string          `json:"device,omitempty"`
+	Value   bool            `json:"value,omitempty"`
+	Request json.RawMessage `json:"request,omitempty"`
+}
+
+type pluginResponse struct {
+	Type     EventType       `json:"type"`
+	Id       string          `json:"id"`
+	Server   string          `json:"server,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+const eventResponse EventType = "response"
+
+func NewProcessPlugin(name string, command string, args ...string) *ProcessPlugin {
+	return &ProcessPlugin{
+		name:         name,
+		command:      command,
+		args:         args,
+		quit:         make(chan struct{}),
+		Timeout:      DefaultPluginTimeout,
+		RestartDelay: 5 * time.Second,
+	}
+}
+
+func (p *ProcessPlugin) Name() string {
+	return p.name
+}
+
+// Starts the program, keeping it running until Close.
+func (p *ProcessPlugin) Start(d *Daemon) error {
+	p.daemon = d
+	done, err := p.startProcess()
+	if err != nil {
+		return err
+	}
+	go p.supervise(done)
+	return nil
+}
+
+// Stops the program by closing its stdin, killing it if it has not exited within a few seconds.
+func (p *ProcessPlugin) Close() error {
+	p.once.Do(func() { close(p.quit) })
+	p.lock.Lock()
+	stdin, process, exited := p.stdin, p.process, p.exited
+	p.lock.Unlock()
+	if stdin == nil {
+		return nil
+	}
+	stdin.Close()
+	timer := time.AfterFunc(5*time.Second, func() { process.Kill() })
+	defer timer.Stop()
+	<-exited
+	return nil
+}
+
+// Sends the event to the program. Events are dropped while it is restarting.
+func (p *ProcessPlugin) HandleEvent(b *Bot, e *Event) {
+	if err := p.send(e); err != nil {
+		p.daemon.logf("plugin %s: %s", p.name, err)
+	}
+}
+
+func (p *ProcessPlugin) supervise(done chan error) {
+	for {
+		select {
+		case <-p.quit:
+			return
+		case err := <-done:
+			p.daemon.logf("plugin %s exited: %v, restarting in %s", p.name, err, p.RestartDelay)
+		}
+		select {
+		case <-p.quit:
+			return
+		case <-time.After(p.RestartDelay):
+		}
+		var err error
+		if done, err = p.startProcess(); err != nil {
+			p.daemon.logf("plugin %s: %s", p.name, err)
+			done = make(chan error, 1)
+			done <- err
+		}
+	}
+}
+
+// Starts the program, returning a channel that receives its exit status.
+func (p *ProcessPlugin) startProcess() (chan error, error) {
+	cmd := exec.Command(p.command, p.args...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting %s: %s", p.command, err)
+	}
+	exited := make(chan struct{})
+	p.lock.Lock()
+	p.stdin, p.process, p.exited = stdin, cmd.Process, exited
+	p.lock.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		p.readCommands(stdout)
+		err := cmd.Wait()
+		p.lock.Lock()
+		p.stdin, p.process = nil, nil
+		p.lock.Unlock()
+		close(exited)
+		done <- err
+	}()
+	return done, nil
+}
+
+func (p *ProcessPlugin) send(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.stdin == nil {
+		return nil
+	}
+	_, err = p.stdin.Write(append(data, '\n'))
+	return err
+}
+
+func (p *ProcessPlugin) readCommands(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		c := &pluginCommand{}
+		if err := json.Unmarshal(scanner.Bytes(), c); err != nil {
+			p.daemon.logf("plugin %s: bad command %q: %s", p.name, scanner.Text(), err)
+			continue
+		}
+		// Requests wait on the server, so they must not hold up the commands behind them.
+		go p.execute(c)
+	}
+}
+
+func (p *ProcessPlugin) execute(c *pluginCommand) {
+	response, err := p.run(c)
+	if c.Id == "" {
+		if err != nil {
+			p.daemon.logf("plugin %s: %s: %s", p.name, c.Action, err)
+		}
+		return
+	}
+	reply := &pluginResponse{Type: eventResponse, Id: c.Id, Server: c.Server}
+	if response != nil {
+		reply.Response, _ = marshalProto(response)
+	}
+	if err != nil {
+		reply.Error = err.Error()
+	}
+	if err := p.send(reply); err != nil {
+		p.daemon.logf("plugin %s: %s", p.name, err)
+	}
+}
+
+func (p *ProcessPlugin) run(c *pluginCommand) (*rustplus.AppResponse, error) {
+	b, err := p.daemon.Bot(c.Server)
+	if err != nil {
+		return nil, err
+	}
+	if !b.Connected() {
+		return nil, fmt.Errorf("%s is not connected", b.Name())
+	}
+	client := b.Client()
+	switch c.Action {
+	case "say":
+		return nil, b.Say(c.Message)
+	case "set":
+		device, err := b.Device(c.Device)
+		if err != nil {
+			return nil, err
+		}
+		req, err := client.NewDeviceSetRequest(device, c.Value)
+		if err != nil {
+			return nil, err
+		}
+		return b.Call(req, p.Timeout)
+	case "request":
+		if len(c.Request) == 0 {
+			return nil, errors.New("request is empty")
+		}
+		req, err := client.NewRawRequest(c.Request)
+		if err != nil {
+			return nil, err
+		}
+		return b.Call(req, p.Timeout)
+	}
+	return nil, fmt.Errorf("unknown action %q", c.Action)
+}
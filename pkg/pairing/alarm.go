@@ -0,0 +1,191 @@
+package pairing
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fishykins/gorustplus/pkg/rustplus"
+)
+
+// Default time allowed between an alarm's push notification and its websocket state change for them to count as one trigger.
+const DefaultAlarmWindow = 5 * time.Second
+
+// A smart alarm going off. Combines the websocket state change with the custom title and message from the push notification.
+type AlarmTriggered struct {
+	// The alarm that went off. Nil if the notification could not be matched to a registered device.
+	Device  *rustplus.Device
+	Title   string
+	Message string
+	Time    time.Time
+	// The push notification, or nil if only the state change was seen.
+	Notification *Notification
+	// True if the state change was seen on the websocket.
+	StateChanged bool
+}
+
+type AlarmEventFunc func(e *AlarmTriggered)
+
+// Pairs alarm push notifications with the matching websocket state changes, raising a single AlarmTriggered for each.
+type AlarmCorrelator struct {
+	client   *rustplus.Client
+	lock     sync.Mutex
+	watched  map[uint32]*rustplus.Device
+	pushes   []*AlarmTriggered
+	states   []*AlarmTriggered
+	handlers map[uint32]AlarmEventFunc
+	hSeq     uint32
+
+	// How long to wait for the other half of a trigger before reporting what we have.
+	Window time.Duration
+}
+
+func NewAlarmCorrelator(c *rustplus.Client) *AlarmCorrelator {
+	return &AlarmCorrelator{
+		client:   c,
+		watched:  make(map[uint32]*rustplus.Device),
+		handlers: make(map[uint32]AlarmEventFunc),
+		Window:   DefaultAlarmWindow,
+	}
+}
+
+// Registers a function to be called whenever an alarm goes off.
+func (a *AlarmCorrelator) AddEventHandler(f AlarmEventFunc) uint32 {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.hSeq++
+	a.handlers[a.hSeq] = f
+	return a.hSeq
+}
+
+// Removes an event handler.
+func (a *AlarmCorrelator) RemoveEventHandler(i uint32) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if _, ok := a.handlers[i]; ok {
+		delete(a.handlers, i)
+		return nil
+	}
+	return fmt.Errorf("event id %d is not registered", i)
+}
+
+// Starts watching an alarm device for state changes.
+func (a *AlarmCorrelator) Watch(d *rustplus.Device) {
+	a.lock.Lock()
+	if _, ok := a.watched[d.GetId()]; ok {
+		a.lock.Unlock()
+		return
+	}
+	a.watched[d.GetId()] = d
+	a.lock.Unlock()
+
+	d.AddBroadcastEvent(func(d *rustplus.Device, b *rustplus.AppEntityPayload) {
+		if b.GetValue() {
+			a.stateChanged(d)
+		}
+	})
+}
+
+// Watches every registered device that is known to be an alarm.
+func (a *AlarmCorrelator) WatchAll() {
+	for _, d := range a.client.GetDevices() {
+		if d.HasType() && d.GetType() == rustplus.AppEntityType_Alarm {
+			a.Watch(d)
+		}
+	}
+}
+
+// Handles an alarm push notification. Notifications for other servers are ignored.
+func (a *AlarmCorrelator) HandleNotification(n *Notification) error {
+	if n.Kind() != AlarmNotification {
+		return nil
+	}
+	if current := a.client.GetConnectionData(); current != nil {
+		port, err := n.Body.GetPort()
+		if err != nil {
+			return fmt.Errorf("alarm: %s", err)
+		}
+		if n.Body.Ip != current.GetIp() || port != current.GetPort() {
+			return nil
+		}
+	}
+
+	e := &AlarmTriggered{Title: n.Title, Message: n.Message, Time: time.Now(), Notification: n}
+	a.lock.Lock()
+	e.Device = a.deviceFor(n)
+	// Look for a state change waiting on this notification.
+	for i, s := range a.states {
+		if e.Device == nil || s.Device == e.Device {
+			s.Title, s.Message, s.Notification = e.Title, e.Message, n
+			a.states = append(a.states[:i], a.states[i+1:]...)
+			a.lock.Unlock()
+			a.fire(s)
+			return nil
+		}
+	}
+	a.pushes = append(a.pushes, e)
+	a.lock.Unlock()
+	time.AfterFunc(a.Window, func() { a.expire(&a.pushes, e) })
+	return nil
+}
+
+// Works out which alarm a notification is for. Newer notifications carry the entity id, otherwise we can only
+// be sure when a single alarm is being watched.
+func (a *AlarmCorrelator) deviceFor(n *Notification) *rustplus.Device {
+	if id, err := strconv.ParseUint(string(n.Body.EntityId), 10, 32); err == nil {
+		if d, ok := a.watched[uint32(id)]; ok {
+			return d
+		}
+	}
+	if len(a.watched) == 1 {
+		for _, d := range a.watched {
+			return d
+		}
+	}
+	return nil
+}
+
+func (a *AlarmCorrelator) stateChanged(d *rustplus.Device) {
+	e := &AlarmTriggered{Device: d, Title: d.Name, Time: time.Now(), StateChanged: true}
+	a.lock.Lock()
+	// Look for a notification waiting on this state change.
+	for i, p := range a.pushes {
+		if p.Device == nil || p.Device == d {
+			p.Device, p.StateChanged = d, true
+			a.pushes = append(a.pushes[:i], a.pushes[i+1:]...)
+			a.lock.Unlock()
+			a.fire(p)
+			return
+		}
+	}
+	a.states = append(a.states, e)
+	a.lock.Unlock()
+	time.AfterFunc(a.Window, func() { a.expire(&a.states, e) })
+}
+
+// Reports a half of a trigger that was never matched, if it is still waiting.
+func (a *AlarmCorrelator) expire(queue *[]*AlarmTriggered, e *AlarmTriggered) {
+	a.lock.Lock()
+	for i, pending := range *queue {
+		if pending == e {
+			*queue = append((*queue)[:i], (*queue)[i+1:]...)
+			a.lock.Unlock()
+			a.fire(e)
+			return
+		}
+	}
+	a.lock.Unlock()
+}
+
+func (a *AlarmCorrelator) fire(e *AlarmTriggered) {
+	a.lock.Lock()
+	handlers := make([]AlarmEventFunc, 0, len(a.handlers))
+	for _, f := range a.handlers {
+		handlers = append(handlers, f)
+	}
+	a.lock.Unlock()
+	for _, f := range handlers {
+		f(e)
+	}
+}
@@ -0,0 +1,428 @@
+package pairing
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Google's mobile connection server, which FCM uses to push messages to devices.
+const FCMHost = "mtalk.google.com:5228"
+
+// Most persistent ids kept for the login request. Older ids have long been acknowledged, so only the newest are sent.
+const maxPersistedIds = 100
+
+const (
+	mcsVersion = 41
+
+	tagHeartbeatPing      = 0
+	tagHeartbeatAck       = 1
+	tagLoginRequest       = 2
+	tagLoginResponse      = 3
+	tagClose              = 4
+	tagDataMessageStanza  = 8
+	aesgcmRecordSize      = 4096
+	aesgcmRecordPadLength = 2
+)
+
+// Credentials for an already registered FCM receiver. These are created when registering for Rust+ notifications,
+// e.g. by the rustplus.js fcm-register command.
+type FCMCredentials struct {
+	AndroidId     string
+	SecurityToken string
+	// Web push keys, base64 encoded. The private key is the raw 32 byte P-256 scalar.
+	PrivateKey string
+	PublicKey  string
+	AuthSecret string
+}
+
+// Loads FCM credentials from a rustplus.js style config file.
+func LoadFCMCredentials(path string) (FCMCredentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return FCMCredentials{}, err
+	}
+	var file struct {
+		Credentials struct {
+			Keys struct {
+				PrivateKey string `json:"privateKey"`
+				PublicKey  string `json:"publicKey"`
+				AuthSecret string `json:"authSecret"`
+			} `json:"keys"`
+			Gcm struct {
+				AndroidId     flexString `json:"androidId"`
+				SecurityToken flexString `json:"securityToken"`
+			} `json:"gcm"`
+		} `json:"fcm_credentials"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return FCMCredentials{}, fmt.Errorf("%s: %s", path, err)
+	}
+	c := file.Credentials
+	creds := FCMCredentials{
+		AndroidId:     string(c.Gcm.AndroidId),
+		SecurityToken: string(c.Gcm.SecurityToken),
+		PrivateKey:    c.Keys.PrivateKey,
+		PublicKey:     c.Keys.PublicKey,
+		AuthSecret:    c.Keys.AuthSecret,
+	}
+	if creds.AndroidId == "" || creds.SecurityToken == "" {
+		return FCMCredentials{}, fmt.Errorf("%s: missing fcm_credentials.gcm androidId or securityToken", path)
+	}
+	return creds, nil
+}
+
+// Receives Rust+ notifications straight from FCM.
+type FCMTransport struct {
+	Credentials FCMCredentials
+	// Address of the connection server. Defaults to FCMHost.
+	Host string
+
+	lock      sync.Mutex
+	persisted []string
+}
+
+func NewFCMTransport(credentials FCMCredentials) *FCMTransport {
+	return &FCMTransport{Credentials: credentials, Host: FCMHost}
+}
+
+func (t *FCMTransport) Listen(ctx context.Context, out chan<- []byte) error {
+	keys, err := t.decodeKeys()
+	if err != nil {
+		return err
+	}
+	dialer := &tls.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", t.Host)
+	if err != nil {
+		return fmt.Errorf("fcm: %s", err)
+	}
+	defer conn.Close()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	login, err := t.loginRequest()
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(append([]byte{mcsVersion}, frame(tagLoginRequest, login)...)); err != nil {
+		return fmt.Errorf("fcm: %s", err)
+	}
+
+	r := bufio.NewReader(conn)
+	if _, err := r.ReadByte(); err != nil {
+		return t.readError(ctx, err)
+	}
+	for {
+		tag, payload, err := readFrame(r)
+		if err != nil {
+			return t.readError(ctx, err)
+		}
+		switch tag {
+		case tagHeartbeatPing:
+			if _, err := conn.Write(frame(tagHeartbeatAck, nil)); err != nil {
+				return t.readError(ctx, err)
+			}
+		case tagClose:
+			return errTransportClosed
+		case tagDataMessageStanza:
+			data, err := t.handleData(payload, keys)
+			if err != nil {
+				// A single bad message should not bring the listener down.
+				continue
+			}
+			select {
+			case out <- data:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+func (t *FCMTransport) readError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return fmt.Errorf("fcm: %s", err)
+}
+
+type pushKeys struct {
+	private    []byte
+	public     []byte
+	authSecret []byte
+}
+
+func (t *FCMTransport) decodeKeys() (pushKeys, error) {
+	private, err := decodeBase64(t.Credentials.PrivateKey)
+	if err != nil {
+		return pushKeys{}, fmt.Errorf("fcm: invalid private key: %s", err)
+	}
+	public, err := decodeBase64(t.Credentials.PublicKey)
+	if err != nil {
+		return pushKeys{}, fmt.Errorf("fcm: invalid public key: %s", err)
+	}
+	secret, err := decodeBase64(t.Credentials.AuthSecret)
+	if err != nil {
+		return pushKeys{}, fmt.Errorf("fcm: invalid auth secret: %s", err)
+	}
+	return pushKeys{private, public, secret}, nil
+}
+
+// Builds an MCS LoginRequest. Persistent ids we have already seen are included so they are not redelivered.
+func (t *FCMTransport) loginRequest() ([]byte, error) {
+	androidId, err := strconv.ParseUint(t.Credentials.AndroidId, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("fcm: invalid android id %q", t.Credentials.AndroidId)
+	}
+	b := make([]byte, 0, 256)
+	b = appendString(b, 1, "chrome-63.0.3234.0")
+	b = appendString(b, 2, "mcs.android.com")
+	b = appendString(b, 3, t.Credentials.AndroidId)
+	b = appendString(b, 4, t.Credentials.AndroidId)
+	b = appendString(b, 5, t.Credentials.SecurityToken)
+	b = appendString(b, 6, "android-"+strconv.FormatUint(androidId, 16))
+	setting := appendString(appendString(nil, 1, "new_vc"), 2, "1")
+	b = protowire.AppendTag(b, 8, protowire.BytesType)
+	b = protowire.AppendBytes(b, setting)
+	t.lock.Lock()
+	for _, id := range t.persisted {
+		b = appendString(b, 10, id)
+	}
+	t.lock.Unlock()
+	b = appendVarint(b, 12, 0)
+	b = appendVarint(b, 14, 1)
+	b = appendVarint(b, 16, 2)
+	b = appendVarint(b, 17, 1)
+	return b, nil
+}
+
+// Pulls the notification out of a DataMessageStanza, decrypting it if need be.
+func (t *FCMTransport) handleData(payload []byte, keys pushKeys) ([]byte, error) {
+	appData := make(map[string]string)
+	var persistentId string
+	var raw []byte
+	for len(payload) > 0 {
+		num, typ, n := protowire.ConsumeTag(payload)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		payload = payload[n:]
+		if typ == protowire.BytesType && (num == 7 || num == 9 || num == 21) {
+			value, n := protowire.ConsumeBytes(payload)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			payload = payload[n:]
+			switch num {
+			case 7:
+				k, v, err := parseAppData(value)
+				if err != nil {
+					return nil, err
+				}
+				appData[k] = v
+			case 9:
+				persistentId = string(value)
+			case 21:
+				raw = value
+			}
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, payload)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		payload = payload[n:]
+	}
+	if persistentId != "" {
+		t.lock.Lock()
+		t.persisted = append(t.persisted, persistentId)
+		if len(t.persisted) > maxPersistedIds {
+			t.persisted = append([]string(nil), t.persisted[len(t.persisted)-maxPersistedIds:]...)
+		}
+		t.lock.Unlock()
+	}
+
+	if raw == nil {
+		return json.Marshal(appData)
+	}
+	dh, err := decodeBase64(headerParam(appData["crypto-key"], "dh"))
+	if err != nil {
+		return nil, fmt.Errorf("fcm: invalid crypto-key: %s", err)
+	}
+	salt, err := decodeBase64(headerParam(appData["encryption"], "salt"))
+	if err != nil {
+		return nil, fmt.Errorf("fcm: invalid encryption salt: %s", err)
+	}
+	return decryptAesgcm(raw, keys, dh, salt)
+}
+
+func parseAppData(b []byte) (string, string, error) {
+	var key, value string
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return "", "", protowire.ParseError(n)
+		}
+		b = b[n:]
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return "", "", protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return "", "", protowire.ParseError(n)
+		}
+		b = b[n:]
+		if num == 1 {
+			key = string(v)
+		} else if num == 2 {
+			value = string(v)
+		}
+	}
+	return key, value, nil
+}
+
+// Decrypts a web push payload using the legacy "aesgcm" content encoding.
+func decryptAesgcm(data []byte, keys pushKeys, senderPublic []byte, salt []byte) ([]byte, error) {
+	curve := elliptic.P256()
+	x, y := elliptic.Unmarshal(curve, senderPublic)
+	if x == nil {
+		return nil, errors.New("fcm: invalid sender public key")
+	}
+	sx, _ := curve.ScalarMult(x, y, keys.private)
+	shared := sx.FillBytes(make([]byte, 32))
+
+	prk := hkdf(keys.authSecret, shared, []byte("Content-Encoding: auth\x00"), 32)
+	context := []byte("P-256\x00")
+	context = append(context, byte(len(keys.public)>>8), byte(len(keys.public)))
+	context = append(context, keys.public...)
+	context = append(context, byte(len(senderPublic)>>8), byte(len(senderPublic)))
+	context = append(context, senderPublic...)
+	cek := hkdf(salt, prk, append([]byte("Content-Encoding: aesgcm\x00"), context...), 16)
+	baseNonce := hkdf(salt, prk, append([]byte("Content-Encoding: nonce\x00"), context...), 12)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, 0, len(data))
+	recordSize := aesgcmRecordSize + gcm.Overhead()
+	for i := 0; len(data) > 0; i++ {
+		n := recordSize
+		if n > len(data) {
+			n = len(data)
+		}
+		nonce := make([]byte, len(baseNonce))
+		copy(nonce, baseNonce)
+		counter := binary.BigEndian.Uint64(append([]byte{0, 0}, nonce[6:]...)) ^ uint64(i)
+		for j := 0; j < 6; j++ {
+			nonce[6+j] = byte(counter >> uint(8*(5-j)))
+		}
+		record, err := gcm.Open(nil, nonce, data[:n], nil)
+		if err != nil {
+			return nil, fmt.Errorf("fcm: decrypting message: %s", err)
+		}
+		if len(record) < aesgcmRecordPadLength {
+			return nil, errors.New("fcm: record too short")
+		}
+		pad := int(binary.BigEndian.Uint16(record))
+		if aesgcmRecordPadLength+pad > len(record) {
+			return nil, errors.New("fcm: invalid record padding")
+		}
+		plain = append(plain, record[aesgcmRecordPadLength+pad:]...)
+		data = data[n:]
+	}
+	return plain, nil
+}
+
+// HKDF with SHA-256, for outputs no longer than a single hash.
+func hkdf(salt, secret, info []byte, length int) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(secret)
+	prk := extract.Sum(nil)
+	expand := hmac.New(sha256.New, prk)
+	expand.Write(info)
+	expand.Write([]byte{1})
+	return expand.Sum(nil)[:length]
+}
+
+// Reads a parameter from a header such as "dh=BHx...;p256ecdsa=BGy...".
+func headerParam(header, name string) string {
+	for _, part := range strings.FieldsFunc(header, func(r rune) bool { return r == ';' || r == ',' }) {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) == 2 && kv[0] == name {
+			return kv[1]
+		}
+	}
+	return ""
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), "=")
+	if s == "" {
+		return nil, errors.New("empty value")
+	}
+	if b, err := base64.RawURLEncoding.DecodeString(s); err == nil {
+		return b, nil
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
+func frame(tag byte, payload []byte) []byte {
+	b := []byte{tag}
+	b = protowire.AppendVarint(b, uint64(len(payload)))
+	return append(b, payload...)
+}
+
+func readFrame(r *bufio.Reader) (byte, []byte, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return tag, payload, nil
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
@@ -0,0 +1,171 @@
+package pairing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/fishykins/gorustplus/pkg/rustplus"
+)
+
+// Something was paired in game.
+type PairingEvent struct {
+	Notification *Notification
+	// Connection data for the server, including the pairing player's token.
+	Connection rustplus.ConnectionData
+	// The paired device. Nil for server pairings.
+	Device *rustplus.Device
+	// True if the device was added to the listener's client.
+	Added bool
+}
+
+type PairingEventFunc func(e *PairingEvent)
+
+// Listens for pairing notifications, adding newly paired devices to the client as they arrive.
+type Listener struct {
+	client    *rustplus.Client
+	transport Transport
+	lock      sync.Mutex
+	devices   map[uint32]PairingEventFunc
+	servers   map[uint32]PairingEventFunc
+	hSeq      uint32
+
+	// Build connection data that goes through the Facepunch proxy.
+	UseProxy bool
+	// Receives alarm notifications, and watches newly paired alarms. Optional.
+	Alarms *AlarmCorrelator
+	// Called when a notification cannot be handled. Optional.
+	OnError func(err error)
+}
+
+// Creates a listener. The client may be nil, in which case devices are reported but never added.
+func NewListener(c *rustplus.Client, t Transport) *Listener {
+	return &Listener{
+		client:    c,
+		transport: t,
+		devices:   make(map[uint32]PairingEventFunc),
+		servers:   make(map[uint32]PairingEventFunc),
+	}
+}
+
+// Registers a function to be called whenever a device is paired.
+func (l *Listener) AddDevicePairedEvent(f PairingEventFunc) uint32 {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.hSeq++
+	l.devices[l.hSeq] = f
+	return l.hSeq
+}
+
+// Registers a function to be called whenever a server is paired.
+func (l *Listener) AddServerPairedEvent(f PairingEventFunc) uint32 {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.hSeq++
+	l.servers[l.hSeq] = f
+	return l.hSeq
+}
+
+// Removes a pairing event.
+func (l *Listener) RemoveEvent(i uint32) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if _, ok := l.devices[i]; ok {
+		delete(l.devices, i)
+		return nil
+	}
+	if _, ok := l.servers[i]; ok {
+		delete(l.servers, i)
+		return nil
+	}
+	return fmt.Errorf("event id %d is not registered", i)
+}
+
+// Handles notifications until the context is cancelled or the transport fails.
+func (l *Listener) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	out := make(chan []byte)
+	done := make(chan error, 1)
+	go func() {
+		done <- l.transport.Listen(ctx, out)
+	}()
+	for {
+		select {
+		case err := <-done:
+			return err
+		case data := <-out:
+			n, err := Parse(data)
+			if err == nil {
+				err = l.Handle(n)
+			}
+			if err != nil && l.OnError != nil {
+				l.OnError(err)
+			}
+		}
+	}
+}
+
+// Handles a single notification. Alarms are passed on to Alarms, anything else that is not a pairing is ignored.
+func (l *Listener) Handle(n *Notification) error {
+	switch n.Kind() {
+	case ServerPairing:
+		connection, err := n.Body.ConnectionData(l.UseProxy)
+		if err != nil {
+			return fmt.Errorf("server pairing: %s", err)
+		}
+		l.fire(l.servers, &PairingEvent{Notification: n, Connection: connection})
+	case EntityPairing:
+		connection, err := n.Body.ConnectionData(l.UseProxy)
+		if err != nil {
+			return fmt.Errorf("entity pairing: %s", err)
+		}
+		device, err := n.Body.Device()
+		if err != nil {
+			return fmt.Errorf("entity pairing: %s", err)
+		}
+		e := &PairingEvent{Notification: n, Connection: connection, Device: device}
+		if l.isClientServer(&connection) {
+			// Keep the pairing player's token, so requests for their devices can be signed with it.
+			l.client.AddToken(connection.Tokens[0])
+			registered, err := l.client.TryGetDevice(device.GetId())
+			if err != nil {
+				if err := l.client.AddDevice(device); err != nil {
+					return fmt.Errorf("entity pairing: %s", err)
+				}
+				registered = device
+				e.Added = true
+			}
+			if l.Alarms != nil && device.GetType() == rustplus.AppEntityType_Alarm {
+				l.Alarms.Watch(registered)
+			}
+		}
+		l.fire(l.devices, e)
+	case AlarmNotification:
+		if l.Alarms != nil {
+			return l.Alarms.HandleNotification(n)
+		}
+	}
+	return nil
+}
+
+// True if the pairing is for the server the client connects to.
+func (l *Listener) isClientServer(c *rustplus.ConnectionData) bool {
+	if l.client == nil || l.client.GetConnectionData() == nil {
+		return false
+	}
+	current := l.client.GetConnectionData()
+	return current.GetIp() == c.GetIp() && current.GetPort() == c.GetPort()
+}
+
+func (l *Listener) fire(handlers map[uint32]PairingEventFunc, e *PairingEvent) {
+	l.lock.Lock()
+	funcs := make([]PairingEventFunc, 0, len(handlers))
+	for _, f := range handlers {
+		funcs = append(funcs, f)
+	}
+	l.lock.Unlock()
+	for _, f := range funcs {
+		f(e)
+	}
+}
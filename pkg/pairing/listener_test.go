@@ -0,0 +1,149 @@
+package pairing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fishykins/gorustplus/pkg/rustplus"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func pairingNotification(ip string, entityId int) []byte {
+	return []byte(fmt.Sprintf(`{"channelId":"pairing","body":{"ip":"%s","port":28017,"type":"entity",`+
+		`"playerId":76561198000000000,"playerToken":-123456789,"entityId":%d,"entityType":1,"entityName":"Switch %d"}}`,
+		ip, entityId, entityId))
+}
+
+// Runs a listener over a LocalTransport, returning a function that stops it and waits for Run to return.
+func runListener(t *testing.T, l *Listener) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- l.Run(ctx)
+	}()
+	return func() {
+		cancel()
+		select {
+		case err := <-done:
+			if err != context.Canceled {
+				t.Errorf("Run returned %v, want %v", err, context.Canceled)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Run did not return after cancel")
+		}
+	}
+}
+
+func TestListenerAddsDevices(t *testing.T) {
+	data := rustplus.NewConnectionData("1.2.3.4", 28017, false)
+	client := rustplus.NewStandaloneClient(&data)
+	transport := NewLocalTransport()
+	l := NewListener(client, transport)
+	events := make(chan *PairingEvent, 4)
+	l.AddDevicePairedEvent(func(e *PairingEvent) { events <- e })
+	stop := runListener(t, l)
+	defer stop()
+
+	transport.Push(pairingNotification("1.2.3.4", 42))
+	transport.Push(pairingNotification("1.2.3.4", 42))
+	transport.Push(pairingNotification("5.6.7.8", 43))
+	for i, want := range []struct {
+		id    uint32
+		added bool
+	}{{42, true}, {42, false}, {43, false}} {
+		select {
+		case e := <-events:
+			if e.Device.GetId() != want.id || e.Added != want.added {
+				t.Errorf("event %d: got device %d added %t, want device %d added %t", i, e.Device.GetId(), e.Added, want.id, want.added)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("event %d was not fired", i)
+		}
+	}
+
+	if _, err := client.TryGetDevice(42); err != nil {
+		t.Error(err)
+	}
+	if _, err := client.TryGetDevice(43); err == nil {
+		t.Error("device paired on another server was added")
+	}
+	if tokens := client.Tokens(); len(tokens) != 1 || tokens[0].Token.SteamId != 76561198000000000 {
+		t.Errorf("got tokens %+v, want the pairing player's", tokens)
+	}
+}
+
+func TestListenerServerPairing(t *testing.T) {
+	transport := NewLocalTransport()
+	l := NewListener(nil, transport)
+	l.UseProxy = true
+	events := make(chan *PairingEvent, 1)
+	errs := make(chan error, 1)
+	l.AddServerPairedEvent(func(e *PairingEvent) { events <- e })
+	l.OnError = func(err error) { errs <- err }
+	stop := runListener(t, l)
+	defer stop()
+
+	transport.Push([]byte(`{"channelId":"pairing","body":"{\"ip\":\"1.2.3.4\","}`))
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("got a nil error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("malformed notification was not reported")
+	}
+
+	transport.Push([]byte(`{"channelId":"pairing","body":` + numericServerBody + `}`))
+	select {
+	case e := <-events:
+		if e.Device != nil || e.Connection.URL() != rustplus.DefaultProxyURL+"/1.2.3.4/28017" {
+			t.Errorf("got device %v url %s", e.Device, e.Connection.URL())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("server pairing was not fired")
+	}
+}
+
+func TestListenerConcurrentDevices(t *testing.T) {
+	data := rustplus.NewConnectionData("1.2.3.4", 28017, false)
+	client := rustplus.NewStandaloneClient(&data)
+	transport := NewLocalTransport()
+	l := NewListener(client, transport)
+	stop := runListener(t, l)
+	defer stop()
+
+	// Run with -race: pairings add devices while the client is being read.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for len(client.GetDevices()) < 20 {
+			client.TryGetDevice(1)
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		transport.Push(pairingNotification("1.2.3.4", i))
+	}
+	wg.Wait()
+}
+
+func TestFCMTransportCapsPersistedIds(t *testing.T) {
+	transport := NewFCMTransport(FCMCredentials{})
+	for i := 0; i < maxPersistedIds+50; i++ {
+		payload := protowire.AppendTag(nil, 9, protowire.BytesType)
+		payload = protowire.AppendString(payload, fmt.Sprintf("0:%d", i))
+		if _, err := transport.handleData(payload, pushKeys{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(transport.persisted) != maxPersistedIds {
+		t.Fatalf("kept %d persistent ids, want %d", len(transport.persisted), maxPersistedIds)
+	}
+	if first, last := transport.persisted[0], transport.persisted[maxPersistedIds-1]; first != "0:50" || last != "0:149" {
+		t.Errorf("kept ids %s to %s, want 0:50 to 0:149", first, last)
+	}
+}
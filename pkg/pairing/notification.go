@@ -0,0 +1,213 @@
+// Package pairing turns Rust+ push notifications into ready to use connection data and devices.
+//
+// Rust+ sends its notifications through Firebase Cloud Messaging. Each one carries a data map like the one below,
+// where body is itself a JSON encoded string:
+//
+//	{
+//	  "channelId": "pairing",
+//	  "title": "Rusty Moose |US Monthly|",
+//	  "message": "Tap to pair with this server.",
+//	  "body": "{\"ip\":\"1.2.3.4\",\"port\":\"28017\",\"name\":\"Rusty Moose |US Monthly|\",\"type\":\"server\",\"playerId\":\"76561198000000000\",\"playerToken\":\"-123456789\"}"
+//	}
+//
+// Entity pairings use the same layout with a type of "entity" and extra entityId, entityType and entityName fields.
+package pairing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fishykins/gorustplus/pkg/rustplus"
+)
+
+type NotificationKind int
+
+const (
+	UnknownNotification NotificationKind = iota
+	ServerPairing
+	EntityPairing
+	AlarmNotification
+)
+
+func (k NotificationKind) String() string {
+	switch k {
+	case ServerPairing:
+		return "server pairing"
+	case EntityPairing:
+		return "entity pairing"
+	case AlarmNotification:
+		return "alarm"
+	}
+	return "unknown"
+}
+
+// A parsed Rust+ push notification.
+type Notification struct {
+	ChannelId string
+	Title     string
+	Message   string
+	Body      Body
+	// Every field of the data map, untouched.
+	Raw map[string]string
+}
+
+// The server, and optionally entity, a notification refers to. The game sends every field as a string.
+type Body struct {
+	Id          string     `json:"id"`
+	Name        string     `json:"name"`
+	Desc        string     `json:"desc"`
+	Img         string     `json:"img"`
+	Logo        string     `json:"logo"`
+	Url         string     `json:"url"`
+	Ip          string     `json:"ip"`
+	Port        flexString `json:"port"`
+	Type        string     `json:"type"`
+	PlayerId    flexString `json:"playerId"`
+	PlayerToken flexString `json:"playerToken"`
+	EntityId    flexString `json:"entityId"`
+	EntityType  flexString `json:"entityType"`
+	EntityName  string     `json:"entityName"`
+}
+
+// A JSON string that tolerates being sent as a bare number.
+type flexString string
+
+func (f *flexString) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*f = flexString(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*f = flexString(n.String())
+	return nil
+}
+
+// Parses a notification's data map. Accepts the map on its own or wrapped in a "data" object, as FCM delivers it.
+func Parse(data []byte) (*Notification, error) {
+	var envelope struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid notification: %s", err)
+	}
+	fields := envelope.Data
+	if fields == nil {
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, fmt.Errorf("invalid notification: %s", err)
+		}
+	}
+	raw := make(map[string]string, len(fields))
+	for k, v := range fields {
+		switch v := v.(type) {
+		case string:
+			raw[k] = v
+		default:
+			// The body is occasionally sent as an object rather than a string.
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid notification field %s: %s", k, err)
+			}
+			raw[k] = string(b)
+		}
+	}
+	return ParseMap(raw)
+}
+
+// Parses a notification from its data map, such as the app data of an FCM message.
+func ParseMap(data map[string]string) (*Notification, error) {
+	n := &Notification{
+		ChannelId: data["channelId"],
+		Title:     data["title"],
+		Message:   data["message"],
+		Raw:       data,
+	}
+	body, ok := data["body"]
+	if !ok || strings.TrimSpace(body) == "" {
+		return nil, errors.New("notification has no body")
+	}
+	if err := json.Unmarshal([]byte(body), &n.Body); err != nil {
+		return nil, fmt.Errorf("invalid notification body: %s", err)
+	}
+	return n, nil
+}
+
+// What the notification is for.
+func (n *Notification) Kind() NotificationKind {
+	switch {
+	case n.ChannelId == "alarm" || n.Body.Type == "alarm":
+		return AlarmNotification
+	case n.Body.Type == "server":
+		return ServerPairing
+	case n.Body.Type == "entity":
+		return EntityPairing
+	}
+	return UnknownNotification
+}
+
+// The server port.
+func (b *Body) GetPort() (uint64, error) {
+	port, err := strconv.ParseUint(string(b.Port), 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid port %q", b.Port)
+	}
+	return port, nil
+}
+
+// The player token used to pair.
+func (b *Body) GetPlayerToken() (rustplus.PlayerToken, error) {
+	steamId, err := strconv.ParseUint(string(b.PlayerId), 10, 64)
+	if err != nil {
+		return rustplus.PlayerToken{}, fmt.Errorf("invalid playerId %q", b.PlayerId)
+	}
+	token, err := strconv.ParseInt(string(b.PlayerToken), 10, 32)
+	if err != nil {
+		return rustplus.PlayerToken{}, fmt.Errorf("invalid playerToken %q", b.PlayerToken)
+	}
+	return rustplus.PlayerToken{SteamId: steamId, Token: int32(token)}, nil
+}
+
+// Connection data for the server, including the player token that paired it.
+func (b *Body) ConnectionData(useProxy bool) (rustplus.ConnectionData, error) {
+	if b.Ip == "" {
+		return rustplus.ConnectionData{}, errors.New("notification has no ip")
+	}
+	port, err := b.GetPort()
+	if err != nil {
+		return rustplus.ConnectionData{}, err
+	}
+	token, err := b.GetPlayerToken()
+	if err != nil {
+		return rustplus.ConnectionData{}, err
+	}
+	data := rustplus.NewConnectionData(b.Ip, port, useProxy)
+	data.AddToken(token)
+	return data, nil
+}
+
+// The paired entity as a device, with its type and owner already set. Only available for entity pairings.
+func (b *Body) Device() (*rustplus.Device, error) {
+	id, err := strconv.ParseUint(string(b.EntityId), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid entityId %q", b.EntityId)
+	}
+	entityType, err := strconv.ParseInt(string(b.EntityType), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid entityType %q", b.EntityType)
+	}
+	if _, ok := rustplus.AppEntityType_name[int32(entityType)]; !ok {
+		return nil, fmt.Errorf("unknown entityType %d", entityType)
+	}
+	device := rustplus.NewDevice(uint32(id), b.EntityName)
+	device.SetType(rustplus.AppEntityType(entityType))
+	if owner, err := strconv.ParseUint(string(b.PlayerId), 10, 64); err == nil {
+		device.Owner = owner
+	}
+	return device, nil
+}
@@ -0,0 +1,198 @@
+package pairing
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fishykins/gorustplus/pkg/rustplus"
+)
+
+const (
+	serverBody = `{"id":"a1b2","name":"Rusty Moose |US Monthly|","desc":"","img":"","logo":"","url":"https://moose.gg",` +
+		`"ip":"1.2.3.4","port":"28017","type":"server","playerId":"76561198000000000","playerToken":"-123456789"}`
+	numericServerBody = `{"ip":"1.2.3.4","port":28017,"name":"Rusty Moose |US Monthly|","type":"server",` +
+		`"playerId":76561198000000000,"playerToken":-123456789}`
+	entityBody = `{"ip":"1.2.3.4","port":"28017","name":"Rusty Moose |US Monthly|","type":"entity",` +
+		`"playerId":"76561198000000000","playerToken":"-123456789","entityId":"123456","entityType":"2","entityName":"Front door"}`
+	numericEntityBody = `{"ip":"1.2.3.4","port":28017,"type":"entity","playerId":76561198000000000,"playerToken":-123456789,` +
+		`"entityId":123456,"entityType":2,"entityName":"Front door"}`
+	alarmBody = `{"ip":"1.2.3.4","port":"28017","name":"Rusty Moose |US Monthly|","type":"alarm",` +
+		`"playerId":"76561198000000000","playerToken":"-123456789"}`
+)
+
+func TestParseMap(t *testing.T) {
+	tests := []struct {
+		name string
+		data map[string]string
+		want Body
+		kind NotificationKind
+	}{
+		{
+			name: "server",
+			data: map[string]string{"channelId": "pairing", "title": "Rusty Moose |US Monthly|", "message": "Tap to pair with this server.", "body": serverBody},
+			want: Body{
+				Id: "a1b2", Name: "Rusty Moose |US Monthly|", Url: "https://moose.gg", Ip: "1.2.3.4", Port: "28017",
+				Type: "server", PlayerId: "76561198000000000", PlayerToken: "-123456789",
+			},
+			kind: ServerPairing,
+		},
+		{
+			name: "server with numeric ids",
+			data: map[string]string{"channelId": "pairing", "body": numericServerBody},
+			want: Body{
+				Name: "Rusty Moose |US Monthly|", Ip: "1.2.3.4", Port: "28017", Type: "server",
+				PlayerId: "76561198000000000", PlayerToken: "-123456789",
+			},
+			kind: ServerPairing,
+		},
+		{
+			name: "entity",
+			data: map[string]string{"channelId": "pairing", "body": entityBody},
+			want: Body{
+				Name: "Rusty Moose |US Monthly|", Ip: "1.2.3.4", Port: "28017", Type: "entity",
+				PlayerId: "76561198000000000", PlayerToken: "-123456789",
+				EntityId: "123456", EntityType: "2", EntityName: "Front door",
+			},
+			kind: EntityPairing,
+		},
+		{
+			name: "entity with numeric ids",
+			data: map[string]string{"channelId": "pairing", "body": numericEntityBody},
+			want: Body{
+				Ip: "1.2.3.4", Port: "28017", Type: "entity", PlayerId: "76561198000000000", PlayerToken: "-123456789",
+				EntityId: "123456", EntityType: "2", EntityName: "Front door",
+			},
+			kind: EntityPairing,
+		},
+		{
+			name: "alarm",
+			data: map[string]string{"channelId": "alarm", "title": "Raid!", "message": "Base is under attack", "body": alarmBody},
+			want: Body{
+				Name: "Rusty Moose |US Monthly|", Ip: "1.2.3.4", Port: "28017", Type: "alarm",
+				PlayerId: "76561198000000000", PlayerToken: "-123456789",
+			},
+			kind: AlarmNotification,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n, err := ParseMap(test.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := &Notification{
+				ChannelId: test.data["channelId"],
+				Title:     test.data["title"],
+				Message:   test.data["message"],
+				Body:      test.want,
+				Raw:       test.data,
+			}
+			if !reflect.DeepEqual(n, want) {
+				t.Errorf("got %+v\nwant %+v", n, want)
+			}
+			if n.Kind() != test.kind {
+				t.Errorf("got kind %s, want %s", n.Kind(), test.kind)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"not json", `{"channelId":`, "invalid notification"},
+		{"missing body", `{"channelId":"pairing","title":"Rusty Moose"}`, "notification has no body"},
+		{"empty body", `{"channelId":"pairing","body":"  "}`, "notification has no body"},
+		{"malformed body", `{"channelId":"pairing","body":"{\"ip\":\"1.2.3.4\","}`, "invalid notification body"},
+		{"body of the wrong type", `{"channelId":"pairing","body":"[1,2]"}`, "invalid notification body"},
+		{"port of the wrong type", `{"channelId":"pairing","body":"{\"port\":true}"}`, "invalid notification body"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n, err := Parse([]byte(test.data))
+			if err == nil {
+				t.Fatalf("got %+v, want an error", n)
+			}
+			if !strings.HasPrefix(err.Error(), test.want) {
+				t.Errorf("got error %q, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestParseFCMEnvelope(t *testing.T) {
+	// FCM wraps the data map, and the body sometimes arrives as an object.
+	n, err := Parse([]byte(`{"data":{"channelId":"pairing","body":` + numericServerBody + `}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Body{
+		Name: "Rusty Moose |US Monthly|", Ip: "1.2.3.4", Port: "28017", Type: "server",
+		PlayerId: "76561198000000000", PlayerToken: "-123456789",
+	}
+	if n.ChannelId != "pairing" || !reflect.DeepEqual(n.Body, want) {
+		t.Errorf("got %s %+v, want pairing %+v", n.ChannelId, n.Body, want)
+	}
+}
+
+func TestBodyConnectionData(t *testing.T) {
+	n, err := ParseMap(map[string]string{"body": numericServerBody})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := n.Body.ConnectionData(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := rustplus.NewConnectionData("1.2.3.4", 28017, true)
+	want.AddToken(rustplus.PlayerToken{SteamId: 76561198000000000, Token: -123456789})
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("got %+v, want %+v", data, want)
+	}
+
+	bad := []Body{
+		{Port: "28017", PlayerId: "1", PlayerToken: "1"},
+		{Ip: "1.2.3.4", Port: "99999", PlayerId: "1", PlayerToken: "1"},
+		{Ip: "1.2.3.4", Port: "28017", PlayerId: "steam", PlayerToken: "1"},
+		{Ip: "1.2.3.4", Port: "28017", PlayerId: "1", PlayerToken: "9999999999"},
+	}
+	for _, b := range bad {
+		if _, err := b.ConnectionData(false); err == nil {
+			t.Errorf("ConnectionData for %+v succeeded, want an error", b)
+		}
+	}
+}
+
+func TestBodyDevice(t *testing.T) {
+	for _, body := range []string{entityBody, numericEntityBody} {
+		n, err := ParseMap(map[string]string{"body": body})
+		if err != nil {
+			t.Fatal(err)
+		}
+		device, err := n.Body.Device()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := rustplus.NewDevice(123456, "Front door")
+		want.SetType(rustplus.AppEntityType_Alarm)
+		want.Owner = 76561198000000000
+		if !reflect.DeepEqual(device, want) {
+			t.Errorf("got %+v, want %+v", device, want)
+		}
+	}
+
+	bad := []Body{
+		{EntityId: "door", EntityType: "1"},
+		{EntityId: "123456", EntityType: "switch"},
+		{EntityId: "123456", EntityType: "42"},
+	}
+	for _, b := range bad {
+		if d, err := b.Device(); err == nil {
+			t.Errorf("Device for %+v = %+v, want an error", b, d)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package pairing
+
+import (
+	"context"
+	"errors"
+)
+
+// Delivers raw push notifications. Each notification is the JSON accepted by Parse.
+type Transport interface {
+	// Sends notifications to out until the context is cancelled or the connection fails.
+	Listen(ctx context.Context, out chan<- []byte) error
+}
+
+// A Transport fed by hand, for tests and for relaying notifications received elsewhere.
+type LocalTransport struct {
+	queue chan []byte
+}
+
+func NewLocalTransport() *LocalTransport {
+	return &LocalTransport{queue: make(chan []byte, 16)}
+}
+
+// Queues a notification for delivery. Blocks if the queue is full.
+func (t *LocalTransport) Push(data []byte) {
+	t.queue <- data
+}
+
+func (t *LocalTransport) Listen(ctx context.Context, out chan<- []byte) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case data := <-t.queue:
+			select {
+			case out <- data:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+var errTransportClosed = errors.New("transport closed")
@@ -0,0 +1,70 @@
+package rustmap
+
+import (
+	"image/color"
+	"strings"
+)
+
+const (
+	glyphWidth  = 3
+	glyphHeight = 5
+)
+
+// A tiny 3x5 bitmap font, so labels can be drawn without pulling in a font renderer.
+// Each row is three bits, most significant bit on the left.
+var glyphs = map[rune][glyphHeight]uint8{
+	'A': {2, 5, 7, 5, 5}, 'B': {6, 5, 6, 5, 6}, 'C': {3, 4, 4, 4, 3}, 'D': {6, 5, 5, 5, 6},
+	'E': {7, 4, 6, 4, 7}, 'F': {7, 4, 6, 4, 4}, 'G': {3, 4, 5, 5, 3}, 'H': {5, 5, 7, 5, 5},
+	'I': {7, 2, 2, 2, 7}, 'J': {1, 1, 1, 5, 2}, 'K': {5, 5, 6, 5, 5}, 'L': {4, 4, 4, 4, 7},
+	'M': {5, 7, 7, 5, 5}, 'N': {6, 5, 5, 5, 5}, 'O': {2, 5, 5, 5, 2}, 'P': {6, 5, 6, 4, 4},
+	'Q': {2, 5, 5, 6, 3}, 'R': {6, 5, 6, 5, 5}, 'S': {3, 4, 2, 1, 6}, 'T': {7, 2, 2, 2, 2},
+	'U': {5, 5, 5, 5, 7}, 'V': {5, 5, 5, 5, 2}, 'W': {5, 5, 7, 7, 5}, 'X': {5, 5, 2, 5, 5},
+	'Y': {5, 5, 2, 2, 2}, 'Z': {7, 1, 2, 4, 7},
+	'0': {7, 5, 5, 5, 7}, '1': {2, 6, 2, 2, 7}, '2': {6, 1, 2, 4, 7}, '3': {6, 1, 2, 1, 6},
+	'4': {5, 5, 7, 1, 1}, '5': {7, 4, 6, 1, 6}, '6': {3, 4, 7, 5, 7}, '7': {7, 1, 2, 2, 2},
+	'8': {7, 5, 7, 5, 7}, '9': {7, 5, 7, 1, 6},
+	' ': {0, 0, 0, 0, 0}, '-': {0, 0, 7, 0, 0}, '.': {0, 0, 0, 0, 2}, ':': {0, 2, 0, 2, 0},
+	'\'': {2, 2, 0, 0, 0}, '/': {1, 1, 2, 4, 4}, '?': {6, 1, 2, 0, 2},
+}
+
+// Width in pixels of the text when drawn at the given scale.
+func textWidth(text string, scale int) int {
+	n := len([]rune(text))
+	if n == 0 {
+		return 0
+	}
+	return (n*(glyphWidth+1) - 1) * scale
+}
+
+// Draws upper cased text with its top left corner at (x, y), outlined so it stays readable on any background.
+// Characters the font does not know are drawn as '?'.
+func (r *Renderer) drawText(x, y int, text string, scale int, c color.RGBA) {
+	text = strings.ToUpper(text)
+	outline := color.RGBA{0, 0, 0, 200}
+	for _, pass := range []struct {
+		offset int
+		colour color.RGBA
+	}{{1, outline}, {0, c}} {
+		cx := x
+		for _, ch := range text {
+			glyph, ok := glyphs[ch]
+			if !ok {
+				glyph = glyphs['?']
+			}
+			for row := 0; row < glyphHeight; row++ {
+				for col := 0; col < glyphWidth; col++ {
+					if glyph[row]&(1<<uint(glyphWidth-1-col)) == 0 {
+						continue
+					}
+					px, py := cx+col*scale, y+row*scale
+					if pass.offset > 0 {
+						r.fillRect(px-pass.offset, py-pass.offset, scale+2*pass.offset, scale+2*pass.offset, pass.colour)
+					} else {
+						r.fillRect(px, py, scale, scale, pass.colour)
+					}
+				}
+			}
+			cx += (glyphWidth + 1) * scale
+		}
+	}
+}
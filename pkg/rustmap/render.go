@@ -0,0 +1,234 @@
+// Package rustmap renders the Rust+ map image with the grid, monuments, team members and markers drawn on top.
+// It is pure Go and only relies on the standard library image packages.
+package rustmap
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+
+	"github.com/fishykins/gorustplus/pkg/rustplus"
+)
+
+var (
+	GridColour        = color.RGBA{0, 0, 0, 90}
+	GridLabelColour   = color.RGBA{255, 255, 255, 255}
+	MonumentColour    = color.RGBA{255, 255, 255, 255}
+	OnlineColour      = color.RGBA{90, 200, 90, 255}
+	OfflineColour     = color.RGBA{160, 160, 160, 255}
+	DeadColour        = color.RGBA{210, 60, 60, 255}
+	CargoShipColour   = color.RGBA{60, 120, 220, 255}
+	CH47Colour        = color.RGBA{230, 200, 40, 255}
+	VendingColour     = color.RGBA{60, 190, 120, 255}
+	CrateColour       = color.RGBA{230, 140, 40, 255}
+	ExplosionColour   = color.RGBA{220, 40, 40, 255}
+	RadiusColour      = color.RGBA{220, 40, 40, 70}
+	NoteColour        = color.RGBA{80, 160, 240, 255}
+	DeathMarkerColour = color.RGBA{30, 30, 30, 255}
+)
+
+// Draws onto a copy of the map image.
+type Renderer struct {
+	img  *image.RGBA
+	grid *rustplus.MapGrid
+	data *rustplus.AppMap
+	// Size of text and icons, in pixels per font pixel. Derived from the grid size by default.
+	Scale int
+}
+
+// Decodes the map image ready for drawing. The map size comes from AppInfo.MapSize.
+func NewRenderer(m *rustplus.AppMap, mapSize uint32) (*Renderer, error) {
+	if m == nil || len(m.JpgImage) == 0 {
+		return nil, errors.New("map has no image")
+	}
+	decoded, err := jpeg.Decode(bytes.NewReader(m.JpgImage))
+	if err != nil {
+		return nil, fmt.Errorf("decoding map image: %s", err)
+	}
+	img := image.NewRGBA(decoded.Bounds())
+	draw.Draw(img, img.Bounds(), decoded, decoded.Bounds().Min, draw.Src)
+
+	grid := rustplus.NewMapGrid(mapSize)
+	grid.SetImage(m)
+	// Trust the image itself if the dimensions were not sent.
+	if grid.Width == 0 || grid.Height == 0 {
+		grid.Width, grid.Height = uint32(img.Bounds().Dx()), uint32(img.Bounds().Dy())
+	}
+
+	r := &Renderer{img: img, grid: grid, data: m, Scale: 1}
+	if cells := grid.Cells(); cells > 0 {
+		cellPixels := (int(grid.Width) - 2*int(grid.OceanMargin)) / cells
+		if s := cellPixels / 30; s > 1 {
+			r.Scale = s
+		}
+	}
+	return r, nil
+}
+
+// The image drawn so far.
+func (r *Renderer) Image() *image.RGBA {
+	return r.img
+}
+
+// The grid used to position everything on the image.
+func (r *Renderer) Grid() *rustplus.MapGrid {
+	return r.grid
+}
+
+// Writes the image drawn so far as a PNG.
+func (r *Renderer) EncodePNG(w io.Writer) error {
+	return png.Encode(w, r.img)
+}
+
+// Draws the grid lines, labelling each square in its top left corner.
+func (r *Renderer) DrawGrid() {
+	cells := r.grid.Cells()
+	for i := 0; i <= cells; i++ {
+		offset := float32(i) * rustplus.GridCellSize
+		x0, y0, _ := r.grid.Pixel(offset, 0)
+		x1, y1, _ := r.grid.Pixel(offset, r.grid.MapSize)
+		r.fillRect(x0, y1, 1, y0-y1, GridColour)
+		x0, y0, _ = r.grid.Pixel(0, r.grid.MapSize-offset)
+		x1, _, _ = r.grid.Pixel(r.grid.MapSize, r.grid.MapSize-offset)
+		r.fillRect(x0, y0, x1-x0, 1, GridColour)
+	}
+	for column := 0; column < cells; column++ {
+		for row := 0; row < cells; row++ {
+			x, y, _ := r.grid.Pixel(float32(column)*rustplus.GridCellSize, r.grid.MapSize-float32(row)*rustplus.GridCellSize)
+			label := rustplus.GridColumnName(column) + fmt.Sprint(row)
+			r.drawText(x+2*r.Scale, y+2*r.Scale, label, r.Scale, GridLabelColour)
+		}
+	}
+}
+
+// Labels every monument on the map with its display name.
+func (r *Renderer) DrawMonuments() {
+	for _, m := range rustplus.NewMonumentIndex(r.data).Monuments() {
+		if m.Category == rustplus.MonumentTunnel {
+			continue
+		}
+		x, y, _ := r.grid.Pixel(m.X, m.Y)
+		r.fillCircle(x, y, 2*r.Scale, MonumentColour)
+		r.drawText(x-textWidth(m.Name, r.Scale)/2, y+3*r.Scale, m.Name, r.Scale, MonumentColour)
+	}
+}
+
+// Draws every team member, coloured by whether they are online, offline or dead.
+func (r *Renderer) DrawTeam(team *rustplus.AppTeamInfo) {
+	for _, member := range team.GetMembers() {
+		c := OfflineColour
+		if !member.GetIsAlive() {
+			c = DeadColour
+		} else if member.GetIsOnline() {
+			c = OnlineColour
+		}
+		x, y, _ := r.grid.Pixel(member.GetX(), member.GetY())
+		r.fillCircle(x, y, 3*r.Scale, color.RGBA{0, 0, 0, 255})
+		r.fillCircle(x, y, 2*r.Scale, c)
+		r.drawText(x-textWidth(member.GetName(), r.Scale)/2, y-9*r.Scale, member.GetName(), r.Scale, c)
+	}
+}
+
+// Draws cargo ships, chinooks, vending machines, crates, explosions and event radii. Player markers are skipped, use DrawTeam instead.
+func (r *Renderer) DrawMarkers(markers []*rustplus.AppMarker) {
+	for _, m := range markers {
+		x, y, _ := r.grid.Pixel(m.GetX(), m.GetY())
+		size := 3 * r.Scale
+		switch m.GetType() {
+		case rustplus.AppMarkerType_CargoShip:
+			r.fillRect(x-2*size, y-size/2, 4*size, size, CargoShipColour)
+		case rustplus.AppMarkerType_CH47:
+			r.fillCircle(x, y, size, CH47Colour)
+		case rustplus.AppMarkerType_VendingMachine:
+			r.fillRect(x-size/2, y-size/2, size, size, VendingColour)
+		case rustplus.AppMarkerType_Crate:
+			r.fillRect(x-size/2, y-size/2, size, size, CrateColour)
+		case rustplus.AppMarkerType_Explosion:
+			r.drawCross(x, y, size, ExplosionColour)
+		case rustplus.AppMarkerType_GenericRadius:
+			// Radius is in world units, so scale it the same way as positions.
+			edge, _, _ := r.grid.Pixel(m.GetX()+m.GetRadius(), m.GetY())
+			r.fillCircle(x, y, edge-x, RadiusColour)
+		}
+	}
+}
+
+// Draws map notes. Death markers are drawn as crosses, anything else as a dot.
+func (r *Renderer) DrawNotes(notes []*rustplus.AppTeamInfo_Note) {
+	for _, n := range notes {
+		x, y, _ := r.grid.Pixel(n.GetX(), n.GetY())
+		if n.GetType() == 0 {
+			r.drawCross(x, y, 2*r.Scale, DeathMarkerColour)
+		} else {
+			r.fillCircle(x, y, 2*r.Scale, NoteColour)
+		}
+	}
+}
+
+// Renders everything available and writes the result as a PNG. Team and markers may be nil.
+func Render(w io.Writer, m *rustplus.AppMap, mapSize uint32, team *rustplus.AppTeamInfo, markers *rustplus.AppMapMarkers) error {
+	r, err := NewRenderer(m, mapSize)
+	if err != nil {
+		return err
+	}
+	r.DrawGrid()
+	r.DrawMonuments()
+	r.DrawMarkers(markers.GetMarkers())
+	r.DrawNotes(team.GetMapNotes())
+	r.DrawNotes(team.GetLeaderMapNotes())
+	r.DrawTeam(team)
+	return r.EncodePNG(w)
+}
+
+//====================================================================================
+//================================ Primitives ========================================
+//====================================================================================
+
+// Alpha blends a colour onto a single pixel.
+func (r *Renderer) blend(x, y int, c color.RGBA) {
+	if !(image.Point{x, y}).In(r.img.Bounds()) {
+		return
+	}
+	if c.A == 255 {
+		r.img.SetRGBA(x, y, c)
+		return
+	}
+	dst := r.img.RGBAAt(x, y)
+	a := uint32(c.A)
+	mix := func(s, d uint8) uint8 {
+		return uint8((uint32(s)*a + uint32(d)*(255-a)) / 255)
+	}
+	r.img.SetRGBA(x, y, color.RGBA{mix(c.R, dst.R), mix(c.G, dst.G), mix(c.B, dst.B), 255})
+}
+
+func (r *Renderer) fillRect(x, y, w, h int, c color.RGBA) {
+	for py := y; py < y+h; py++ {
+		for px := x; px < x+w; px++ {
+			r.blend(px, py, c)
+		}
+	}
+}
+
+func (r *Renderer) fillCircle(cx, cy, radius int, c color.RGBA) {
+	for y := -radius; y <= radius; y++ {
+		span := int(math.Sqrt(float64(radius*radius - y*y)))
+		for x := -span; x <= span; x++ {
+			r.blend(cx+x, cy+y, c)
+		}
+	}
+}
+
+func (r *Renderer) drawCross(cx, cy, size int, c color.RGBA) {
+	thickness := r.Scale
+	for i := -size; i <= size; i++ {
+		r.fillRect(cx+i, cy+i, thickness, thickness, c)
+		r.fillRect(cx+i, cy-i, thickness, thickness, c)
+	}
+}
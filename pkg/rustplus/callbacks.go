@@ -56,6 +56,9 @@ func (dcb DeviceCallback) Call(m *AppResponse) {
 
 	// Update cached values.
 	if m.EntityInfo != nil {
+		if !dcb.device.HasType() && m.EntityInfo.Type != nil {
+			dcb.device.SetType(*m.EntityInfo.Type)
+		}
 		dcb.device.SetData(m.EntityInfo.Payload)
 	}
 }
@@ -95,3 +98,75 @@ func (cb *MapCallback) Call(m *AppResponse) {
 func NewMapCb(inner func(data *AppMap)) *MapCallback {
 	return &MapCallback{inner}
 }
+
+//====================================================================================
+//=============================== Chat Callback ======================================
+//====================================================================================
+
+type ChatCallback struct {
+	inner func(chat *AppTeamChat)
+}
+
+func (cb *ChatCallback) Call(m *AppResponse) {
+	if cb.inner != nil && m.TeamChat != nil {
+		cb.inner(m.TeamChat)
+	}
+}
+
+func NewChatCb(inner func(chat *AppTeamChat)) *ChatCallback {
+	return &ChatCallback{inner}
+}
+
+//====================================================================================
+//=============================== Time Callback ======================================
+//====================================================================================
+
+type TimeCallback struct {
+	inner func(t *AppTime)
+}
+
+func (cb *TimeCallback) Call(m *AppResponse) {
+	if cb.inner != nil && m.Time != nil {
+		cb.inner(m.Time)
+	}
+}
+
+func NewTimeCb(inner func(t *AppTime)) *TimeCallback {
+	return &TimeCallback{inner}
+}
+
+//====================================================================================
+//============================== Markers Callback ====================================
+//====================================================================================
+
+type MarkersCallback struct {
+	inner func(markers *AppMapMarkers)
+}
+
+func (cb *MarkersCallback) Call(m *AppResponse) {
+	if cb.inner != nil && m.MapMarkers != nil {
+		cb.inner(m.MapMarkers)
+	}
+}
+
+func NewMarkersCb(inner func(markers *AppMapMarkers)) *MarkersCallback {
+	return &MarkersCallback{inner}
+}
+
+//====================================================================================
+//============================== Camera Callback =====================================
+//====================================================================================
+
+type CameraCallback struct {
+	inner func(frame *AppCameraFrame)
+}
+
+func (cb *CameraCallback) Call(m *AppResponse) {
+	if cb.inner != nil && m.CameraFrame != nil {
+		cb.inner(m.CameraFrame)
+	}
+}
+
+func NewCameraCb(inner func(frame *AppCameraFrame)) *CameraCallback {
+	return &CameraCallback{inner}
+}
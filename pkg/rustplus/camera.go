@@ -0,0 +1,261 @@
+package rustplus
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// Content type to serve WriteMJPEG output with.
+	MJPEGContentType = "multipart/x-mixed-replace; boundary=" + mjpegBoundary
+	mjpegBoundary    = "frame"
+)
+
+// A decoded camera frame.
+type CameraFrame struct {
+	Camera string
+	Frame  uint32
+	Time   time.Time
+	Jpeg   []byte
+	Image  image.Image
+}
+
+// Fetches successive frames from a CCTV camera or drone at a target frame rate.
+type CameraStream struct {
+	client     *Client
+	identifier string
+	frames     chan *CameraFrame
+	quit       chan struct{}
+	once       sync.Once
+
+	lock      sync.Mutex
+	closed    bool
+	next      uint32
+	pending   bool
+	seq       uint32
+	requested time.Time
+	misses    int
+	dropped   uint64
+
+	// Decoded frames. Closed once the stream has stopped.
+	Frames <-chan *CameraFrame
+	// Target frames per second.
+	FPS float64
+	// How long to wait for a frame before asking again.
+	Timeout time.Duration
+	// Consecutive failures on a single frame before it is skipped.
+	MaxMisses int
+	// Called when a frame is missing or fails to decode. Optional.
+	OnError func(err error)
+}
+
+// Creates a stream for the camera with the given identifier. Call Start to begin fetching frames.
+func NewCameraStream(c *Client, identifier string, fps float64) *CameraStream {
+	frames := make(chan *CameraFrame, 8)
+	return &CameraStream{
+		client:     c,
+		identifier: identifier,
+		frames:     frames,
+		quit:       make(chan struct{}),
+		Frames:     frames,
+		FPS:        fps,
+		Timeout:    5 * time.Second,
+		MaxMisses:  3,
+	}
+}
+
+func (s *CameraStream) Identifier() string {
+	return s.identifier
+}
+
+// Number of frames discarded because nobody was reading them.
+func (s *CameraStream) Dropped() uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.dropped
+}
+
+// Starts fetching frames in the background.
+func (s *CameraStream) Start() error {
+	if s.FPS <= 0 {
+		return errors.New("fps must be positive")
+	}
+	go s.run()
+	return nil
+}
+
+// Stops fetching frames and closes Frames.
+func (s *CameraStream) Stop() {
+	s.once.Do(func() {
+		close(s.quit)
+	})
+}
+
+func (s *CameraStream) run() {
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / s.FPS))
+	defer ticker.Stop()
+	defer func() {
+		s.lock.Lock()
+		s.closed = true
+		close(s.frames)
+		s.lock.Unlock()
+	}()
+	for {
+		s.request()
+		select {
+		case <-s.quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Asks for the next frame, unless we are still waiting on the last one.
+func (s *CameraStream) request() {
+	s.lock.Lock()
+	if s.pending {
+		if time.Since(s.requested) < s.Timeout {
+			s.lock.Unlock()
+			return
+		}
+		seq, frame := s.seq, s.next
+		s.lock.Unlock()
+		// A late reply would otherwise be taken for the frame asked for next.
+		s.client.Cancel(seq)
+		s.fail(fmt.Errorf("camera %s: frame %d timed out", s.identifier, frame))
+		s.lock.Lock()
+	}
+	frame := s.next
+	request, err := s.client.NewCameraRequest(s.identifier, frame)
+	if err != nil {
+		s.lock.Unlock()
+		s.fail(fmt.Errorf("camera %s: %s", s.identifier, err))
+		return
+	}
+	seq := request.GetSeq()
+	s.pending = true
+	s.seq = seq
+	s.requested = time.Now()
+	s.lock.Unlock()
+
+	err = s.client.Write(request, NewPrimitiveCb(func(m *AppResponse) {
+		s.receive(seq, frame, m)
+	}))
+	if err != nil {
+		s.fail(fmt.Errorf("camera %s: %s", s.identifier, err))
+	}
+}
+
+// Whether seq is the request the stream is waiting on.
+func (s *CameraStream) current(seq uint32) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.pending && s.seq == seq
+}
+
+func (s *CameraStream) receive(seq uint32, frame uint32, m *AppResponse) {
+	if !s.current(seq) {
+		return
+	}
+	if m.Error != nil {
+		s.fail(fmt.Errorf("camera %s: frame %d: %s", s.identifier, frame, m.Error.GetError()))
+		return
+	}
+	if m.CameraFrame == nil || len(m.CameraFrame.JpgImage) == 0 {
+		s.fail(fmt.Errorf("camera %s: frame %d missing", s.identifier, frame))
+		return
+	}
+	img, err := jpeg.Decode(bytes.NewReader(m.CameraFrame.JpgImage))
+	if err != nil {
+		s.fail(fmt.Errorf("camera %s: frame %d: %s", s.identifier, frame, err))
+		return
+	}
+	decoded := &CameraFrame{
+		Camera: s.identifier,
+		Frame:  m.CameraFrame.GetFrame(),
+		Time:   time.Now(),
+		Jpeg:   m.CameraFrame.JpgImage,
+		Image:  img,
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.pending || s.seq != seq {
+		return
+	}
+	s.pending = false
+	s.misses = 0
+	s.next = decoded.Frame + 1
+	if s.closed {
+		return
+	}
+	select {
+	case s.frames <- decoded:
+	default:
+		s.dropped++
+	}
+}
+
+// Records a failed frame, skipping past it once it has failed too many times.
+func (s *CameraStream) fail(err error) {
+	s.lock.Lock()
+	s.pending = false
+	s.misses++
+	if s.MaxMisses > 0 && s.misses >= s.MaxMisses {
+		s.next++
+		s.misses = 0
+	}
+	s.lock.Unlock()
+	if s.OnError != nil {
+		s.OnError(err)
+	}
+}
+
+// Writes frames to w as a multipart MJPEG stream until the stream stops. Serve it with MJPEGContentType.
+func (s *CameraStream) WriteMJPEG(w io.Writer) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(mjpegBoundary); err != nil {
+		return err
+	}
+	for frame := range s.Frames {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", "image/jpeg")
+		header.Set("Content-Length", strconv.Itoa(len(frame.Jpeg)))
+		part, err := mw.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		if _, err := part.Write(frame.Jpeg); err != nil {
+			return err
+		}
+		if f, ok := w.(interface{ Flush() }); ok {
+			f.Flush()
+		}
+	}
+	return mw.Close()
+}
+
+// Saves each frame into dir as <camera>_<frame>.jpg until the stream stops.
+func (s *CameraStream) SaveFrames(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for frame := range s.Frames {
+		name := filepath.Join(dir, fmt.Sprintf("%s_%06d.jpg", frame.Camera, frame.Frame))
+		if err := os.WriteFile(name, frame.Jpeg, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
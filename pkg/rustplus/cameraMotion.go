@@ -0,0 +1,187 @@
+package rustplus
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sync"
+	"time"
+)
+
+// Motion spotted between two camera frames.
+type MotionEvent struct {
+	// The frame the motion was spotted in.
+	Frame *CameraFrame
+	// Fraction of the watched area that changed.
+	Changed float64
+	// Bounding box of the changed pixels, in frame coordinates.
+	Region image.Rectangle
+}
+
+type MotionEventFunc func(e *MotionEvent)
+
+// Spots motion by comparing each camera frame with the one before it.
+type MotionDetector struct {
+	lock     sync.Mutex
+	previous []uint8
+	bounds   image.Rectangle
+	last     time.Time
+	handlers map[uint32]MotionEventFunc
+	hSeq     uint32
+
+	// Fraction of the watched area that must change to count as motion, between 0 and 1.
+	Sensitivity float64
+	// How much a pixel's brightness must change, out of 255, to count as changed. Filters out compression noise.
+	Threshold uint8
+	// Only every Step'th pixel in each direction is compared.
+	Step int
+	// Regions of the frame to ignore, such as a flickering light or a window onto the road.
+	Masks []image.Rectangle
+	// Minimum time between two events.
+	Cooldown time.Duration
+}
+
+// Creates a detector that triggers when the given fraction of the frame changes.
+func NewMotionDetector(sensitivity float64) *MotionDetector {
+	return &MotionDetector{
+		handlers:    make(map[uint32]MotionEventFunc),
+		Sensitivity: sensitivity,
+		Threshold:   25,
+		Step:        2,
+		Cooldown:    5 * time.Second,
+	}
+}
+
+// Registers a function to be called whenever motion is spotted.
+func (d *MotionDetector) AddEventHandler(f MotionEventFunc) uint32 {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.hSeq++
+	d.handlers[d.hSeq] = f
+	return d.hSeq
+}
+
+// Removes an event handler.
+func (d *MotionDetector) RemoveEventHandler(i uint32) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if _, ok := d.handlers[i]; ok {
+		delete(d.handlers, i)
+		return nil
+	}
+	return fmt.Errorf("event id %d is not registered", i)
+}
+
+// Forgets the previous frame, so the next one is treated as the first.
+func (d *MotionDetector) Reset() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.previous = nil
+}
+
+// Feeds frames from the stream into the detector until the stream stops. This consumes the stream's frames.
+func (d *MotionDetector) Watch(s *CameraStream) {
+	go func() {
+		for frame := range s.Frames {
+			d.Feed(frame)
+		}
+	}()
+}
+
+// Compares the frame with the previous one, returning an event if motion was spotted.
+func (d *MotionDetector) Feed(frame *CameraFrame) (*MotionEvent, bool) {
+	if frame == nil || frame.Image == nil {
+		return nil, false
+	}
+	step := d.Step
+	if step < 1 {
+		step = 1
+	}
+	bounds := frame.Image.Bounds()
+	current := sampleLuminance(frame.Image, step)
+
+	d.lock.Lock()
+	previous := d.previous
+	sameSize := bounds.Eq(d.bounds)
+	d.previous = current
+	d.bounds = bounds
+	if previous == nil || !sameSize || len(previous) != len(current) {
+		d.lock.Unlock()
+		return nil, false
+	}
+
+	watched, changed := 0, 0
+	region := image.Rectangle{}
+	i := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			p := image.Point{x, y}
+			a, b := previous[i], current[i]
+			i++
+			if d.masked(p) {
+				continue
+			}
+			watched++
+			if absDiff(a, b) < d.Threshold {
+				continue
+			}
+			changed++
+			region = region.Union(image.Rect(x, y, x+step, y+step))
+		}
+	}
+	if watched == 0 {
+		d.lock.Unlock()
+		return nil, false
+	}
+	fraction := float64(changed) / float64(watched)
+	if changed == 0 || fraction < d.Sensitivity || time.Since(d.last) < d.Cooldown {
+		d.lock.Unlock()
+		return nil, false
+	}
+	d.last = time.Now()
+	handlers := make([]MotionEventFunc, 0, len(d.handlers))
+	for _, f := range d.handlers {
+		handlers = append(handlers, f)
+	}
+	d.lock.Unlock()
+
+	e := &MotionEvent{Frame: frame, Changed: fraction, Region: region.Intersect(bounds)}
+	for _, f := range handlers {
+		f(e)
+	}
+	return e, true
+}
+
+func (d *MotionDetector) masked(p image.Point) bool {
+	for _, m := range d.Masks {
+		if p.In(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// Samples the brightness of every step'th pixel, row by row.
+func sampleLuminance(img image.Image, step int) []uint8 {
+	b := img.Bounds()
+	samples := make([]uint8, 0, ((b.Dx()+step-1)/step)*((b.Dy()+step-1)/step))
+	ycbcr, isYCbCr := img.(*image.YCbCr)
+	for y := b.Min.Y; y < b.Max.Y; y += step {
+		for x := b.Min.X; x < b.Max.X; x += step {
+			// JPEGs decode to YCbCr, which already carries the brightness.
+			if isYCbCr {
+				samples = append(samples, ycbcr.Y[ycbcr.YOffset(x, y)])
+			} else {
+				samples = append(samples, color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+			}
+		}
+	}
+	return samples
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
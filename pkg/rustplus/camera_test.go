@@ -0,0 +1,55 @@
+package rustplus
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func cameraResponse(t *testing.T, seq uint32, frame uint32) *AppResponse {
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, image.NewGray(image.Rect(0, 0, 4, 4)), nil); err != nil {
+		t.Fatal(err)
+	}
+	return &AppResponse{
+		Seq:         proto.Uint32(seq),
+		CameraFrame: &AppCameraFrame{Frame: proto.Uint32(frame), JpgImage: b.Bytes()},
+	}
+}
+
+func TestCameraStreamDropsStaleReplies(t *testing.T) {
+	s := NewCameraStream(&Client{}, "CAM1", 1)
+	s.pending, s.seq, s.next = true, 7, 3
+
+	// A reply to a request that already timed out.
+	s.receive(6, 2, cameraResponse(t, 6, 2))
+	if len(s.frames) != 0 || !s.pending || s.next != 3 {
+		t.Fatalf("stale reply was used: %d frames, pending %t, next %d", len(s.frames), s.pending, s.next)
+	}
+
+	s.receive(7, 3, cameraResponse(t, 7, 3))
+	if len(s.frames) != 1 || s.pending || s.next != 4 {
+		t.Fatalf("current reply was not used: %d frames, pending %t, next %d", len(s.frames), s.pending, s.next)
+	}
+	if frame := <-s.Frames; frame.Frame != 3 || frame.Camera != "CAM1" {
+		t.Errorf("got frame %d from %s, want frame 3 from CAM1", frame.Frame, frame.Camera)
+	}
+}
+
+func TestClientCancel(t *testing.T) {
+	c := &Client{callbacks: map[uint32]Callback{}, pending: map[uint32]*AppRequest{}}
+	called := false
+	c.callbacks[1] = NewPrimitiveCb(func(m *AppResponse) { called = true })
+	c.pending[1] = &AppRequest{Seq: proto.Uint32(1)}
+
+	c.Cancel(1)
+	if err := c.handleResponse(&AppResponse{Seq: proto.Uint32(1)}); err != nil {
+		t.Fatal(err)
+	}
+	if called || len(c.pending) != 0 {
+		t.Errorf("cancelled request was still answered: called %t, %d pending", called, len(c.pending))
+	}
+}
@@ -0,0 +1,142 @@
+package rustplus
+
+import (
+	"sort"
+	"sync"
+)
+
+// Default number of messages kept by a ChatHistory.
+const DefaultChatHistoryLimit = 256
+
+// Default number of message keys remembered for spotting duplicates. Comfortably more than the server's backlog,
+// so a sync never brings back a message that has already been emitted.
+const DefaultChatSeenLimit = 4096
+
+type ChatMessageFunc func(m *AppChatMessage)
+
+// Identifies a chat message. The game gives messages no id of their own.
+type chatKey struct {
+	steamId uint64
+	time    uint32
+	message string
+}
+
+func newChatKey(m *AppChatMessage) chatKey {
+	return chatKey{m.GetSteamId(), m.GetTime(), m.GetMessage()}
+}
+
+// Merges the team chat backlog with live broadcasts so that every message is emitted exactly once,
+// even across reconnects. Once attached to a client, messages are also relayed to chat subscribers.
+type ChatHistory struct {
+	client   *Client
+	lock     sync.Mutex
+	seen     map[chatKey]struct{}
+	keys     []chatKey
+	messages []*AppChatMessage
+	handlers map[uint32]ChatMessageFunc
+	hSeq     uint32
+
+	// Number of messages to remember. Older messages are forgotten first.
+	Limit int
+	// Number of messages to recognise as already emitted. Kept separate from Limit, so forgetting a message does not
+	// make it new again. Never less than Limit.
+	SeenLimit int
+}
+
+// Creates a chat history and attaches it to the client. The backlog is fetched every time the client connects.
+func NewChatHistory(c *Client) *ChatHistory {
+	h := &ChatHistory{
+		client:    c,
+		seen:      make(map[chatKey]struct{}),
+		messages:  make([]*AppChatMessage, 0),
+		handlers:  make(map[uint32]ChatMessageFunc),
+		Limit:     DefaultChatHistoryLimit,
+		SeenLimit: DefaultChatSeenLimit,
+	}
+	c.chatHistory = h
+	return h
+}
+
+// Registers a function to be called once for every new message.
+func (h *ChatHistory) AddHandler(f ChatMessageFunc) uint32 {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.hSeq++
+	h.handlers[h.hSeq] = f
+	return h.hSeq
+}
+
+// Removes a message handler.
+func (h *ChatHistory) RemoveHandler(i uint32) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	delete(h.handlers, i)
+}
+
+// Returns a copy of the remembered messages, oldest first.
+func (h *ChatHistory) Messages() []*AppChatMessage {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	messages := make([]*AppChatMessage, len(h.messages))
+	copy(messages, h.messages)
+	return messages
+}
+
+// Requests the team chat backlog and merges it into the history.
+func (h *ChatHistory) Sync() error {
+	return h.client.GetTeamChat(func(chat *AppTeamChat) {
+		h.Merge(chat.Messages)
+	})
+}
+
+// Merges a batch of messages into the history, emitting any we have not seen yet in chronological order.
+func (h *ChatHistory) Merge(messages []*AppChatMessage) {
+	sorted := make([]*AppChatMessage, len(messages))
+	copy(sorted, messages)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].GetTime() < sorted[j].GetTime()
+	})
+	for _, m := range sorted {
+		h.Add(m)
+	}
+}
+
+// Adds a single message to the history. Returns false if it has already been seen.
+func (h *ChatHistory) Add(m *AppChatMessage) bool {
+	if m == nil {
+		return false
+	}
+	h.lock.Lock()
+	key := newChatKey(m)
+	if _, ok := h.seen[key]; ok {
+		h.lock.Unlock()
+		return false
+	}
+	h.seen[key] = struct{}{}
+	h.keys = append(h.keys, key)
+	h.messages = append(h.messages, m)
+	for h.Limit > 0 && len(h.messages) > h.Limit {
+		h.messages = h.messages[1:]
+	}
+	seenLimit := h.SeenLimit
+	if seenLimit < h.Limit {
+		seenLimit = h.Limit
+	}
+	for seenLimit > 0 && len(h.keys) > seenLimit {
+		delete(h.seen, h.keys[0])
+		h.keys = h.keys[1:]
+	}
+	handlers := make([]ChatMessageFunc, 0, len(h.handlers))
+	for _, f := range h.handlers {
+		handlers = append(handlers, f)
+	}
+	h.lock.Unlock()
+
+	for _, f := range handlers {
+		f(m)
+	}
+	if h.client != nil {
+		h.client.publishChat(m)
+	}
+	return true
+}
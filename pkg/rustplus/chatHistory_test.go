@@ -0,0 +1,70 @@
+package rustplus
+
+import "testing"
+
+func chatMessage(steamId uint64, time uint32, text string) *AppChatMessage {
+	name := "player"
+	color := "#fff"
+	return &AppChatMessage{SteamId: &steamId, Name: &name, Message: &text, Color: &color, Time: &time}
+}
+
+func TestChatHistoryEmitsOnce(t *testing.T) {
+	backlog := []*AppChatMessage{
+		chatMessage(1, 100, "first"),
+		chatMessage(2, 101, "second"),
+		chatMessage(1, 102, "third"),
+	}
+	h := NewChatHistory(&Client{})
+	h.Limit = 2
+	emitted := 0
+	h.AddHandler(func(m *AppChatMessage) { emitted++ })
+
+	h.Merge(backlog)
+	h.Merge(backlog)
+	if emitted != 3 {
+		t.Errorf("emitted %d messages, want 3", emitted)
+	}
+	if got := len(h.Messages()); got != 2 {
+		t.Errorf("kept %d messages, want 2", got)
+	}
+	if got := h.Messages()[0].GetMessage(); got != "second" {
+		t.Errorf("oldest kept message is %q, want %q", got, "second")
+	}
+}
+
+func TestChatHistoryMergeOrder(t *testing.T) {
+	h := NewChatHistory(&Client{})
+	order := make([]string, 0)
+	h.AddHandler(func(m *AppChatMessage) { order = append(order, m.GetMessage()) })
+
+	h.Add(chatMessage(1, 105, "live"))
+	h.Merge([]*AppChatMessage{
+		chatMessage(2, 103, "b"),
+		chatMessage(1, 105, "live"),
+		chatMessage(2, 101, "a"),
+	})
+	want := []string{"live", "a", "b"}
+	if len(order) != len(want) {
+		t.Fatalf("emitted %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("emitted %v, want %v", order, want)
+		}
+	}
+}
+
+func TestChatHistorySeenLimit(t *testing.T) {
+	h := NewChatHistory(&Client{})
+	h.Limit = 1
+	h.SeenLimit = 2
+	h.Add(chatMessage(1, 1, "a"))
+	h.Add(chatMessage(1, 2, "b"))
+	h.Add(chatMessage(1, 3, "c"))
+	if h.Add(chatMessage(1, 2, "b")) {
+		t.Error("message within SeenLimit was emitted again")
+	}
+	if !h.Add(chatMessage(1, 1, "a")) {
+		t.Error("message beyond SeenLimit was not treated as new")
+	}
+}
@@ -0,0 +1,148 @@
+package rustplus
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// The longest team message the game will display before truncating.
+	MaxChatMessageLength = 128
+	// Default gap between queued messages, keeping us clear of the server's chat throttle.
+	DefaultChatInterval = time.Second
+	// Default number of chunks that can be waiting to be sent.
+	DefaultChatQueueSize = 64
+)
+
+// Queues outgoing team messages, splitting long text into game sized chunks and pacing them out
+// so the server does not throttle us.
+type ChatSender struct {
+	client *Client
+	queue  chan string
+	quit   chan struct{}
+	once   sync.Once
+
+	// Prepended to every chunk so humans can tell bot messages apart. Leave empty to disable.
+	Prefix string
+	// Maximum length of a single chunk, including the prefix.
+	MaxLength int
+	// Minimum time between two messages.
+	Interval time.Duration
+	// Called when a queued message fails to send. Optional.
+	OnError func(message string, err error)
+}
+
+// Creates a new chat sender for the given client. Call Start to begin sending.
+func NewChatSender(c *Client, prefix string) *ChatSender {
+	return &ChatSender{
+		client:    c,
+		queue:     make(chan string, DefaultChatQueueSize),
+		quit:      make(chan struct{}),
+		Prefix:    prefix,
+		MaxLength: MaxChatMessageLength,
+		Interval:  DefaultChatInterval,
+	}
+}
+
+// Splits the message into chunks and queues them for sending. Returns an error if the queue is full,
+// in which case no part of the message is queued.
+func (s *ChatSender) Send(message string) error {
+	chunks, err := SplitMessage(message, s.MaxLength-utf8.RuneCountInString(s.Prefix))
+	if err != nil {
+		return err
+	}
+	if len(chunks) > cap(s.queue)-len(s.queue) {
+		return errors.New("chat queue is full")
+	}
+	for _, chunk := range chunks {
+		s.queue <- s.Prefix + chunk
+	}
+	return nil
+}
+
+// Number of chunks waiting to be sent.
+func (s *ChatSender) Pending() int {
+	return len(s.queue)
+}
+
+// Starts sending queued messages in the background.
+func (s *ChatSender) Start() {
+	go s.run()
+}
+
+// Stops sending. Anything left in the queue is discarded.
+func (s *ChatSender) Stop() {
+	s.once.Do(func() {
+		close(s.quit)
+	})
+}
+
+func (s *ChatSender) run() {
+	var last time.Time
+	for {
+		select {
+		case <-s.quit:
+			return
+		case message := <-s.queue:
+			if wait := s.Interval - time.Since(last); wait > 0 {
+				select {
+				case <-s.quit:
+					return
+				case <-time.After(wait):
+				}
+			}
+			if err := s.write(message); err != nil && s.OnError != nil {
+				s.OnError(message, err)
+			}
+			last = time.Now()
+		}
+	}
+}
+
+func (s *ChatSender) write(message string) error {
+	req, err := s.client.NewChatWriteRequest(message)
+	if err != nil {
+		return err
+	}
+	return s.client.Write(req, nil)
+}
+
+// Splits the message on word boundaries into chunks no longer than maxLength runes.
+// Words that do not fit on their own are broken up.
+func SplitMessage(message string, maxLength int) ([]string, error) {
+	if maxLength <= 0 {
+		return nil, errors.New("max length leaves no room for the message")
+	}
+	chunks := make([]string, 0)
+	current := ""
+	for _, word := range strings.Fields(message) {
+		// Break up any words that are too long to ever fit.
+		for utf8.RuneCountInString(word) > maxLength {
+			if current != "" {
+				chunks = append(chunks, current)
+				current = ""
+			}
+			runes := []rune(word)
+			chunks = append(chunks, string(runes[:maxLength]))
+			word = string(runes[maxLength:])
+		}
+		if word == "" {
+			continue
+		}
+		if current == "" {
+			current = word
+		} else if utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) <= maxLength {
+			current += " " + word
+		} else {
+			chunks = append(chunks, current)
+			current = word
+		}
+	}
+	if current != "" {
+		chunks = append(chunks, current)
+	}
+	return chunks, nil
+}
@@ -0,0 +1,81 @@
+package rustplus
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		message   string
+		maxLength int
+		want      []string
+	}{
+		{"short", "open the door", 20, []string{"open the door"}},
+		{"empty", "  ", 20, []string{}},
+		{"word boundaries", "open the front door now", 10, []string{"open the", "front door", "now"}},
+		{"exact fit", "abcde fghij", 11, []string{"abcde fghij"}},
+		{"collapses whitespace", "open\n\tthe   door", 20, []string{"open the door"}},
+		{"long word", "abcdefghijklm", 5, []string{"abcde", "fghij", "klm"}},
+		{"long word after text", "hi abcdefgh yo", 4, []string{"hi", "abcd", "efgh", "yo"}},
+		{"counts runes", "ééé ééé", 3, []string{"ééé", "ééé"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := SplitMessage(test.message, test.maxLength)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+			for _, chunk := range got {
+				if n := utf8.RuneCountInString(chunk); n > test.maxLength {
+					t.Errorf("chunk %q is %d runes, longer than %d", chunk, n, test.maxLength)
+				}
+			}
+		})
+	}
+
+	if _, err := SplitMessage("hi", 0); err == nil {
+		t.Error("max length of zero was accepted")
+	}
+}
+
+func TestChatSenderQueue(t *testing.T) {
+	s := NewChatSender(&Client{}, "[bot] ")
+	s.MaxLength = 16
+	if err := s.Send("open the front door please"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"[bot] open the", "[bot] front door", "[bot] please"}
+	if s.Pending() != len(want) {
+		t.Fatalf("queued %d chunks, want %d", s.Pending(), len(want))
+	}
+	for _, w := range want {
+		if got := <-s.queue; got != w {
+			t.Errorf("queued %q, want %q", got, w)
+		}
+	}
+
+	// A message that does not fit is refused whole.
+	for i := 0; i < DefaultChatQueueSize-1; i++ {
+		if err := s.Send("x"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Send(strings.Repeat("word ", 10)); err == nil {
+		t.Error("message was queued past the queue size")
+	}
+	if s.Pending() != DefaultChatQueueSize-1 {
+		t.Errorf("%d chunks pending, want %d", s.Pending(), DefaultChatQueueSize-1)
+	}
+
+	s.Prefix = strings.Repeat("!", 16)
+	if err := s.Send("hi"); err == nil {
+		t.Error("prefix filling the whole message was accepted")
+	}
+}
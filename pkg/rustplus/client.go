@@ -3,6 +3,8 @@ package rustplus
 import (
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"google.golang.org/protobuf/proto"
@@ -10,65 +12,151 @@ import (
 
 var client = Client{}
 
+// The AppError given to callbacks still waiting on a response when the connection is closed or replaced.
+const ConnectionClosed = "connection_closed"
+
 type Client struct {
 	connectionData *ConnectionData
 	connection     *websocket.Conn
 	seq            uint32
 	devices        map[uint32]*Device
 	callbacks      map[uint32]Callback
+	chatHistory    *ChatHistory
+	chatBroker     broker
+	teamBroker     broker
+	cache          serverCache
+	tokenPolicy    TokenPolicy
+	tokenIndex     int
+	badTokens      map[uint64]error
+	pending        map[uint32]*AppRequest
+	verified       map[uint64]bool
+	verifyTokens   bool
+	verifyTimeout  time.Duration
+	serverName     string
+	dialOptions    DialOptions
+	lock           sync.Mutex
+	writeLock      sync.Mutex
 
 	// Assigning channels will cause the client to block until the channel is read. use with caution!
+	// Prefer SubscribeChat and SubscribeTeam, which give each consumer its own queue.
 	Chat chan *AppChatMessage
 	Team chan *AppTeamChanged
 }
 
 // Instantiates a new static client. This will overwrite any existing client so be careful!
 func NewClient(connectionData *ConnectionData) *Client {
-	client = Client{
-		connectionData: connectionData,
-		seq:            0,
-		devices:        make(map[uint32]*Device),
-		callbacks:      make(map[uint32]Callback),
-		Chat:           nil,
-	}
+	client = Client{}
+	client.init(connectionData)
 	return &client
 }
 
+// Instantiates a client separate from the static one, for talking to several servers at once.
+// Devices added to it read and write through it rather than the static client.
+func NewStandaloneClient(connectionData *ConnectionData) *Client {
+	c := &Client{}
+	c.init(connectionData)
+	return c
+}
+
+func (c *Client) init(connectionData *ConnectionData) {
+	c.connectionData = connectionData
+	c.devices = make(map[uint32]*Device)
+	c.callbacks = make(map[uint32]Callback)
+	c.badTokens = make(map[uint64]error)
+	c.pending = make(map[uint32]*AppRequest)
+	c.verified = make(map[uint64]bool)
+}
+
 // Gets the static client
 func GetClient() *Client {
 	return &client
 }
 
+func (c *Client) GetConnectionData() *ConnectionData {
+	return c.connectionData
+}
+
 func (c *Client) Connect() error {
-	var err error
-	c.connection, _, err = websocket.DefaultDialer.Dial(c.connectionData.URL(), nil)
+	dialer, err := c.dialOptions.dialer()
+	if err != nil {
+		return err
+	}
+	conn, _, err := dialer.Dial(c.connectionData.URL(), c.dialOptions.Header)
 	if err != nil {
 		return err
 	}
-	if len(c.devices) > 0 {
-		for _, device := range c.devices {
-			c.initDevice(device)
+	// The connection is only published once the handshake is done, so a read loop still running cannot take its replies.
+	if c.verifyTokens {
+		if err := c.handshake(conn); err != nil {
+			conn.Close()
+			return err
 		}
 	}
+	// Responses to anything sent over an earlier connection will never arrive.
+	c.failPending()
+	c.setConnection(conn)
+	for _, device := range c.GetDevices() {
+		c.initDevice(device)
+	}
+	// Catch up on anything said while we were away.
+	if c.chatHistory != nil {
+		return c.chatHistory.Sync()
+	}
 	return nil
 }
 
 func (c *Client) Connected() bool {
-	return c.connection != nil
+	return c.conn() != nil
 }
 
+// Closes the connection. Requests still waiting on a response are answered with a ConnectionClosed error.
 func (c *Client) Disconnect() error {
-	err := c.connection.Close()
-	if err != nil {
-		return err
+	conn := c.setConnection(nil)
+	if conn == nil {
+		return nil
+	}
+	err := conn.Close()
+	c.failPending()
+	return err
+}
+
+// The connection is swapped under writeLock, as it is only ever written to while holding it.
+func (c *Client) conn() *websocket.Conn {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	return c.connection
+}
+
+// Replaces the connection, returning the old one.
+func (c *Client) setConnection(conn *websocket.Conn) *websocket.Conn {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	old := c.connection
+	c.connection = conn
+	return old
+}
+
+// Answers every request still waiting on a response with a ConnectionClosed error, so nothing is left waiting on a
+// connection that has gone, and starts afresh.
+func (c *Client) failPending() {
+	c.lock.Lock()
+	callbacks := c.callbacks
+	c.callbacks = make(map[uint32]Callback)
+	c.pending = make(map[uint32]*AppRequest)
+	c.lock.Unlock()
+	for seq, cb := range callbacks {
+		seq := seq
+		cb.Call(&AppResponse{Seq: &seq, Error: &AppError{Error: proto.String(ConnectionClosed)}})
 	}
-	return nil
 }
 
 // Adds a device to the client and initializes it.
 func (c *Client) AddDevice(device *Device) error {
+	c.lock.Lock()
 	c.devices[device.GetId()] = device
-	if c.connection != nil {
+	c.lock.Unlock()
+	device.client = c
+	if c.Connected() {
 		return c.initDevice(device)
 	}
 	return nil
@@ -76,6 +164,8 @@ func (c *Client) AddDevice(device *Device) error {
 
 // Removes a device from the client.
 func (c *Client) RemoveDevice(d Device) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	if _, ok := c.devices[d.GetId()]; ok {
 		delete(c.devices, d.GetId())
 		return nil
@@ -83,29 +173,75 @@ func (c *Client) RemoveDevice(d Device) error {
 	return fmt.Errorf("device not found: %d", d.GetId())
 }
 
+// All registered devices.
+func (c *Client) GetDevices() []*Device {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	devices := make([]*Device, 0, len(c.devices))
+	for _, d := range c.devices {
+		devices = append(devices, d)
+	}
+	return devices
+}
+
 func (c *Client) TryGetDevice(id uint32) (*Device, error) {
-	if _, ok := c.devices[id]; ok {
-		return c.devices[id], nil
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if device, ok := c.devices[id]; ok {
+		return device, nil
 	}
 	return nil, fmt.Errorf("device not found: %d", id)
 }
 
 // Writes the request to websocket and prep callback if provided.
+// If the server rejects the request's player token, it is sent again with the next working token.
 func (c *Client) Write(request *AppRequest, callback Callback) error {
+	c.lock.Lock()
+	if callback != nil {
+		c.callbacks[*request.Seq] = callback
+	}
+	c.pending[*request.Seq] = request
+	c.lock.Unlock()
+	if err := c.send(request); err != nil {
+		c.Cancel(*request.Seq)
+		return err
+	}
+	return nil
+}
+
+// Forgets a request written with Write, so its callback is not called if the response turns up later.
+func (c *Client) Cancel(seq uint32) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.callbacks, seq)
+	delete(c.pending, seq)
+}
+
+func (c *Client) send(request *AppRequest) error {
 	data, err := proto.Marshal(request)
 	if err != nil {
 		return err
 	}
-	c.connection.WriteMessage(websocket.BinaryMessage, data)
-	if callback != nil {
-		c.callbacks[*request.Seq] = callback
+	// The websocket only supports one concurrent writer.
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	if c.connection == nil {
+		return errors.New("not connected")
 	}
-	return nil
+	return c.connection.WriteMessage(websocket.BinaryMessage, data)
 }
 
 // Reads a message from the websocket. This is a blocking call.
 func (c *Client) Read() (*AppMessage, error) {
-	_, message, err := c.connection.ReadMessage()
+	conn := c.conn()
+	if conn == nil {
+		return nil, errors.New("connection error: not connected")
+	}
+	return readMessage(conn)
+}
+
+func readMessage(conn *websocket.Conn) (*AppMessage, error) {
+	_, message, err := conn.ReadMessage()
 	if err != nil {
 		return nil, fmt.Errorf("connection error: %s", err)
 	}
@@ -122,7 +258,8 @@ func (c *Client) Read() (*AppMessage, error) {
 	return nil, nil
 }
 
-// Handles the given message, executing any callbacks.
+// Handles the given message, executing any callbacks. Broadcasts for devices that were never added return an error,
+// which read loops that do not track every device can ignore.
 func (c *Client) HandleMessage(message *AppMessage) error {
 	if message == nil {
 		return errors.New("message is nil")
@@ -143,6 +280,8 @@ func (c *Client) HandleMessage(message *AppMessage) error {
 // =====================================================================================================================
 
 func (c *Client) GetSeq() uint32 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	s := c.seq
 	c.seq++
 	return s
@@ -153,10 +292,19 @@ func (c *Client) handleResponse(r *AppResponse) error {
 		return errors.New("response is nil")
 	}
 
-	if c.callbacks[*r.Seq] != nil {
-		cb := c.callbacks[*r.Seq]
+	// The callback stays registered while the request is retried with another token.
+	if c.failover(r) {
+		return nil
+	}
+
+	c.lock.Lock()
+	cb := c.callbacks[*r.Seq]
+	delete(c.callbacks, *r.Seq)
+	delete(c.pending, *r.Seq)
+	c.lock.Unlock()
+
+	if cb != nil {
 		cb.Call(r)
-		delete(c.callbacks, *r.Seq)
 	}
 	return nil
 }
@@ -167,7 +315,10 @@ func (c *Client) handleBroadcast(b *AppBroadcast) error {
 	}
 	if b.EntityChanged != nil {
 		entityId := *b.EntityChanged.EntityId
-		if device, ok := c.devices[entityId]; ok {
+		c.lock.Lock()
+		device, ok := c.devices[entityId]
+		c.lock.Unlock()
+		if ok {
 			payload := b.EntityChanged.Payload
 			device.BroadcastEvent(payload)
 			device.SetData(payload)
@@ -175,28 +326,29 @@ func (c *Client) handleBroadcast(b *AppBroadcast) error {
 			return fmt.Errorf("device not found: %d", entityId)
 		}
 	}
-	if b.TeamMessage != nil && c.Chat != nil {
-		c.Chat <- b.TeamMessage.Message
+	if b.TeamMessage != nil {
+		if c.chatHistory != nil {
+			// The history relays new messages itself.
+			c.chatHistory.Add(b.TeamMessage.Message)
+		} else {
+			c.publishChat(b.TeamMessage.Message)
+		}
 	}
 
-	if b.TeamChanged != nil && c.Team != nil {
-		c.Team <- b.TeamChanged
+	if b.TeamChanged != nil {
+		c.publishTeam(b.TeamChanged)
 	}
 	return nil
 }
 
 // Send a request for device info so we can specify the device type
 func (c *Client) initDevice(device *Device) error {
-	id := device.GetId()
-	name := device.Name
-	fmt.Printf("%s (%d) init...\n", name, id)
+	fmt.Printf("%s (%d) init...\n", device.Name, device.GetId())
 
-	req, err := c.NewRequest()
+	req, err := c.NewDeviceGetRequest(device)
 	if err != nil {
 		return err
 	}
-	req.EntityId = &id
-	req.GetEntityInfo = &AppEmpty{}
 	cb, _ := NewDeviceCallback(device, device.onInit)
 	return c.Write(req, cb)
 }
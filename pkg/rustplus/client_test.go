@@ -0,0 +1,106 @@
+package rustplus
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// Writes a request the test server never answers, returning a channel that receives whatever its callback is given.
+func writeUnanswered(t *testing.T, c *Client) chan *AppResponse {
+	responses := make(chan *AppResponse, 1)
+	req, err := c.NewTimeRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Write(req, NewPrimitiveCb(func(r *AppResponse) { responses <- r })); err != nil {
+		t.Fatal(err)
+	}
+	return responses
+}
+
+func expectClosed(t *testing.T, responses chan *AppResponse) {
+	select {
+	case r := <-responses:
+		if r.GetError().GetError() != ConnectionClosed {
+			t.Errorf("got %v, want a %s error", r, ConnectionClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pending callback was not called")
+	}
+}
+
+func TestDisconnectFailsPending(t *testing.T) {
+	c := handshakeClient(t, goodPlayer)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	responses := writeUnanswered(t, c)
+	if err := c.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+	expectClosed(t, responses)
+
+	if c.Connected() {
+		t.Error("client still connected after Disconnect")
+	}
+	if err := c.Disconnect(); err != nil {
+		t.Errorf("second Disconnect: %s", err)
+	}
+	if _, err := c.Read(); err == nil {
+		t.Error("Read succeeded without a connection")
+	}
+	if len(c.callbacks) != 0 || len(c.pending) != 0 {
+		t.Errorf("%d callbacks and %d requests left pending", len(c.callbacks), len(c.pending))
+	}
+}
+
+func TestReconnectFailsPending(t *testing.T) {
+	c := handshakeClient(t, goodPlayer)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	responses := writeUnanswered(t, c)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Disconnect()
+	expectClosed(t, responses)
+}
+
+func TestConcurrentReconnect(t *testing.T) {
+	c := handshakeClient(t, goodPlayer)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Disconnect()
+
+	// Run with -race: requests are written while the connection is replaced, as they are when a bot reconnects.
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if req, err := c.NewTimeRequest(); err == nil {
+				c.Write(req, NewPrimitiveCb(func(r *AppResponse) {}))
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			c.Disconnect()
+			if err := c.Connect(); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	wg.Wait()
+}
@@ -1,11 +1,18 @@
 package rustplus
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
+
+// Where proxied connections go unless SetProxyURL says otherwise.
+const DefaultProxyURL = "wss://companion-rust.facepunch.com/game"
 
 type ConnectionData struct {
 	useProxy bool
 	ip       string
 	port     uint64
+	proxyURL string
 	Tokens   []PlayerToken
 }
 
@@ -22,9 +29,35 @@ func (c *ConnectionData) AddToken(token PlayerToken) {
 	c.Tokens = append(c.Tokens, token)
 }
 
+func (c *ConnectionData) GetIp() string {
+	return c.ip
+}
+
+func (c *ConnectionData) GetPort() uint64 {
+	return c.port
+}
+
+func (c *ConnectionData) UsesProxy() bool {
+	return c.useProxy
+}
+
+// Routes proxied connections through another companion proxy or relay, e.g. "wss://relay.example.com/game".
+// The server ip and port are appended to it. An empty url restores DefaultProxyURL.
+func (c *ConnectionData) SetProxyURL(base string) {
+	c.proxyURL = strings.TrimSuffix(base, "/")
+}
+
+// The base url used for proxied connections.
+func (c *ConnectionData) ProxyURL() string {
+	if c.proxyURL == "" {
+		return DefaultProxyURL
+	}
+	return c.proxyURL
+}
+
 func (c *ConnectionData) URL() string {
 	if c.useProxy {
-		return fmt.Sprintf("wss://companion-rust.facepunch.com/game/%s/%d", c.ip, c.port)
+		return fmt.Sprintf("%s/%s/%d", c.ProxyURL(), c.ip, c.port)
 	} else {
 		return fmt.Sprintf("ws://%s:%d", c.ip, c.port)
 	}
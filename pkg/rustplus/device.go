@@ -2,17 +2,24 @@ package rustplus
 
 import (
 	"fmt"
+	"sync"
 )
 
 // A device with a known type
 type Device struct {
-	id         uint32
-	Name       string
+	id    uint32
+	Name  string
+	Group string
+	// Steam id of the player that paired the device. Requests for it use their token when possible.
+	Owner      uint64
 	value      *bool
 	entityType *AppEntityType
 	onInit     DeviceCallbackFunc
 	onUpdate   map[uint32]BroadcastEvent
 	uSeq       uint32
+	client     *Client
+	// Guards onUpdate, which is read on the client's read loop. A pointer, as devices are handed to callbacks by value.
+	lock *sync.Mutex
 }
 
 func NewDevice(id uint32, name string) *Device {
@@ -23,6 +30,7 @@ func NewDevice(id uint32, name string) *Device {
 		entityType: nil,
 		onInit:     nil,
 		onUpdate:   make(map[uint32]BroadcastEvent),
+		lock:       &sync.Mutex{},
 	}
 }
 
@@ -34,6 +42,11 @@ func (d *Device) GetType() AppEntityType {
 	return *d.entityType
 }
 
+// True once the device type is known, either from pairing or the init response.
+func (d *Device) HasType() bool {
+	return d.entityType != nil
+}
+
 func (d *Device) SetType(t AppEntityType) error {
 	if d.entityType != nil {
 		return fmt.Errorf("device type already set")
@@ -52,6 +65,8 @@ func (d *Device) GetValue() bool {
 
 // Registers a bradcast event for this device.
 func (d *Device) AddBroadcastEvent(f BroadcastEvent) uint32 {
+	d.lock.Lock()
+	defer d.lock.Unlock()
 	d.uSeq++
 	d.onUpdate[d.uSeq] = f
 	return d.uSeq
@@ -59,6 +74,8 @@ func (d *Device) AddBroadcastEvent(f BroadcastEvent) uint32 {
 
 // Removes an update event from the device
 func (d *Device) RemoveUpdateEvent(i uint32) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
 	if _, ok := d.onUpdate[i]; ok {
 		delete(d.onUpdate, i)
 		return nil
@@ -68,18 +85,24 @@ func (d *Device) RemoveUpdateEvent(i uint32) error {
 
 // Called by broadcast handler before values are set.
 func (d *Device) BroadcastEvent(b *AppEntityPayload) {
-	if d.entityType == nil {
-		for _, f := range d.onUpdate {
-			f(d, b)
-		}
+	// Prevent update spamming from Storagebox, as it sends the same update twice with both true and false.
+	if d.entityType != nil && *d.entityType == AppEntityType_StorageMonitor && !*b.Value {
 		return
 	}
-	// Prevent update spamming from Storagebox, as it sends the same update twice with both true and false.
-	if *d.entityType != AppEntityType_StorageMonitor || (*d.entityType == AppEntityType_StorageMonitor && *b.Value) {
-		for _, f := range d.onUpdate {
-			f(d, b)
-		}
+	// Handlers are called without the lock held, so they can add or remove events themselves.
+	for _, f := range d.updateEvents() {
+		f(d, b)
+	}
+}
+
+func (d *Device) updateEvents() []BroadcastEvent {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	events := make([]BroadcastEvent, 0, len(d.onUpdate))
+	for _, f := range d.onUpdate {
+		events = append(events, f)
 	}
+	return events
 }
 
 // Sets the device values using websocket payload. Called after BroadcastEvent.
@@ -90,11 +113,17 @@ func (d *Device) SetData(b *AppEntityPayload) {
 
 // A quick and simple way to write value to the websocket with no callback.
 func (d *Device) WriteValue(value bool) error {
-	c := GetClient()
-	return c.SetDeviceInfo(d, value, nil)
+	return d.getClient().SetDeviceInfo(d, value, nil)
 }
 
 func (d *Device) ReadValue(callback DeviceCallbackFunc) error {
-	c := GetClient()
-	return c.GetDeviceInfo(d, callback)
+	return d.getClient().GetDeviceInfo(d, callback)
+}
+
+// The client the device was added to, falling back to the static client.
+func (d *Device) getClient() *Client {
+	if d.client != nil {
+		return d.client
+	}
+	return GetClient()
 }
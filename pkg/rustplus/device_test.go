@@ -0,0 +1,61 @@
+package rustplus
+
+import (
+	"sync"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDeviceEventsWhileBroadcasting(t *testing.T) {
+	d := NewDevice(1, "door")
+	d.SetType(AppEntityType_Switch)
+	payload := &AppEntityPayload{Value: proto.Bool(true)}
+
+	// Run with -race: handlers are added and removed while the read loop calls them.
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			id := d.AddBroadcastEvent(func(d *Device, b *AppEntityPayload) {})
+			if err := d.RemoveUpdateEvent(id); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			d.BroadcastEvent(payload)
+		}
+	}()
+	wg.Wait()
+}
+
+func TestDeviceEventCanRemoveItself(t *testing.T) {
+	d := NewDevice(1, "door")
+	calls := 0
+	var id uint32
+	id = d.AddBroadcastEvent(func(d *Device, b *AppEntityPayload) {
+		calls++
+		d.RemoveUpdateEvent(id)
+	})
+	d.BroadcastEvent(&AppEntityPayload{Value: proto.Bool(true)})
+	d.BroadcastEvent(&AppEntityPayload{Value: proto.Bool(true)})
+	if calls != 1 {
+		t.Errorf("handler was called %d times, want 1", calls)
+	}
+}
+
+func TestStorageMonitorIgnoresFalseUpdates(t *testing.T) {
+	d := NewDevice(1, "tc")
+	d.SetType(AppEntityType_StorageMonitor)
+	calls := 0
+	d.AddBroadcastEvent(func(d *Device, b *AppEntityPayload) { calls++ })
+	d.BroadcastEvent(&AppEntityPayload{Value: proto.Bool(false)})
+	d.BroadcastEvent(&AppEntityPayload{Value: proto.Bool(true)})
+	if calls != 1 {
+		t.Errorf("handler was called %d times, want 1", calls)
+	}
+}
@@ -0,0 +1,128 @@
+package rustplus
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Tracks the in-game time of day, extrapolating between polls so that it only needs refreshing occasionally.
+// In-game time is measured in hours, from 0 up to (but not including) 24.
+type GameClock struct {
+	lock             sync.RWMutex
+	time             float64
+	sunrise          float64
+	sunset           float64
+	dayLengthMinutes float64
+	timeScale        float64
+	updated          time.Time
+}
+
+// Creates a clock from a GetTime response.
+func NewGameClock(t *AppTime) *GameClock {
+	g := &GameClock{}
+	g.Update(t)
+	return g
+}
+
+// Resets the clock using a fresh GetTime response.
+func (g *GameClock) Update(t *AppTime) {
+	if t == nil {
+		return
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.time = float64(t.GetTime())
+	g.sunrise = float64(t.GetSunrise())
+	g.sunset = float64(t.GetSunset())
+	g.dayLengthMinutes = float64(t.GetDayLengthMinutes())
+	g.timeScale = float64(t.GetTimeScale())
+	g.updated = time.Now()
+}
+
+// Requests the current time from the server and updates the clock when it arrives.
+func (g *GameClock) Refresh(c *Client) error {
+	return c.GetTime(g.Update)
+}
+
+// When the clock was last updated from the server.
+func (g *GameClock) UpdatedAt() time.Time {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.updated
+}
+
+// The estimated in-game time right now.
+func (g *GameClock) Now() float32 {
+	return g.At(time.Now())
+}
+
+// The estimated in-game time at the given real-world time.
+func (g *GameClock) At(t time.Time) float32 {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return float32(wrapHours(g.time + t.Sub(g.updated).Seconds()*g.rate()))
+}
+
+// In-game hours that pass per real-world second.
+// Assumes a full day lasts DayLengthMinutes, sped up by TimeScale.
+func (g *GameClock) rate() float64 {
+	if g.dayLengthMinutes <= 0 {
+		return 0
+	}
+	scale := g.timeScale
+	if scale <= 0 {
+		scale = 1
+	}
+	return 24 * scale / (g.dayLengthMinutes * 60)
+}
+
+// True if the sun is currently up.
+func (g *GameClock) IsDay() bool {
+	now := float64(g.Now())
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return now >= g.sunrise && now < g.sunset
+}
+
+// Real-world time until the sun next sets.
+func (g *GameClock) UntilNight() time.Duration {
+	return g.until(func(g *GameClock) float64 { return g.sunset })
+}
+
+// Real-world time until the sun next rises.
+func (g *GameClock) UntilDay() time.Duration {
+	return g.until(func(g *GameClock) float64 { return g.sunrise })
+}
+
+func (g *GameClock) until(target func(g *GameClock) float64) time.Duration {
+	now := float64(g.Now())
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	rate := g.rate()
+	if rate == 0 {
+		return 0
+	}
+	hours := wrapHours(target(g) - now)
+	return time.Duration(hours / rate * float64(time.Second))
+}
+
+// The current in-game time, formatted like "21:34".
+func (g *GameClock) String() string {
+	return FormatGameTime(g.Now())
+}
+
+// Formats in-game hours as a 24 hour clock, e.g. 21.5666 becomes "21:34".
+func FormatGameTime(hours float32) string {
+	minutes := int(math.Floor(wrapHours(float64(hours)) * 60))
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+func wrapHours(hours float64) float64 {
+	hours = math.Mod(hours, 24)
+	if hours < 0 {
+		hours += 24
+	}
+	return hours
+}
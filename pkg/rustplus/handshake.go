@@ -0,0 +1,141 @@
+package rustplus
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/proto"
+)
+
+// How long Connect waits for the server to answer each token check.
+const DefaultHandshakeTimeout = 10 * time.Second
+
+// Returned by Connect when token verification is on and the server accepted none of the tokens.
+type TokenError struct {
+	Tokens []TokenStatus
+}
+
+func (e *TokenError) Error() string {
+	reasons := make([]string, 0, len(e.Tokens))
+	for _, t := range e.Tokens {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", t.Token, t.Err))
+	}
+	if len(reasons) == 0 {
+		return "no player tokens"
+	}
+	return "no valid player tokens (" + strings.Join(reasons, "; ") + ")"
+}
+
+// Makes Connect check every token with a GetInfo request before returning. Tokens the server rejects are taken out
+// of use, and Connect fails with a TokenError if none are left. Tokens are checked again on every connect, so a
+// refreshed token comes back into use. A timeout of zero uses DefaultHandshakeTimeout.
+func (c *Client) VerifyTokens(enabled bool, timeout time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if timeout <= 0 {
+		timeout = DefaultHandshakeTimeout
+	}
+	c.verifyTokens = enabled
+	c.verifyTimeout = timeout
+}
+
+// The server name reported during the connect handshake. Empty unless tokens were verified.
+func (c *Client) ServerName() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.serverName
+}
+
+// Sends GetInfo once per token and waits for each answer. Anything else read in the meantime is handled as normal.
+func (c *Client) handshake(conn *websocket.Conn) error {
+	c.lock.Lock()
+	tokens := append([]PlayerToken(nil), c.connectionData.Tokens...)
+	timeout := c.verifyTimeout
+	c.serverName = ""
+	c.verified = make(map[uint64]bool)
+	c.lock.Unlock()
+
+	defer conn.SetReadDeadline(time.Time{})
+	for _, token := range tokens {
+		info, err := c.checkToken(conn, token, timeout)
+		if _, rejected := err.(*rejectedError); err != nil && !rejected {
+			// The connection is no use after a read error or timeout, and other server errors say nothing about the
+			// token, so give up without taking it out of use.
+			return fmt.Errorf("handshake: %s", err)
+		}
+		c.lock.Lock()
+		if err != nil {
+			c.disableToken(token.SteamId, err)
+		} else {
+			delete(c.badTokens, token.SteamId)
+			c.verified[token.SteamId] = true
+			c.serverName = info.GetName()
+		}
+		c.lock.Unlock()
+		if info != nil {
+			c.updateInfo(info)
+		}
+	}
+
+	status := c.Tokens()
+	for _, t := range status {
+		if t.Err == nil {
+			return nil
+		}
+	}
+	return &TokenError{Tokens: status}
+}
+
+type rejectedError struct {
+	reason string
+}
+
+func (e *rejectedError) Error() string {
+	return "rejected by server: " + e.reason
+}
+
+func (c *Client) checkToken(conn *websocket.Conn, token PlayerToken, timeout time.Duration) (*AppInfo, error) {
+	seq := c.GetSeq()
+	req := &AppRequest{
+		Seq:         &seq,
+		PlayerId:    &token.SteamId,
+		PlayerToken: &token.Token,
+		GetInfo:     &AppEmpty{},
+	}
+	data, err := proto.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	// Nothing else writes to the connection until the handshake is over.
+	if err := conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+		return nil, err
+	}
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	for {
+		message, err := readMessage(conn)
+		if err != nil {
+			return nil, err
+		}
+		if message == nil {
+			continue
+		}
+		if message.Response == nil || message.Response.GetSeq() != seq {
+			c.HandleMessage(message)
+			continue
+		}
+		r := message.Response
+		if IsAuthError(r.Error) {
+			return nil, &rejectedError{r.Error.GetError()}
+		}
+		// Anything else, such as rate_limit, says nothing about the token.
+		if r.Error != nil {
+			return nil, fmt.Errorf("server error: %s", r.Error.GetError())
+		}
+		if r.Info == nil {
+			return nil, fmt.Errorf("no info in response")
+		}
+		return r.Info, nil
+	}
+}
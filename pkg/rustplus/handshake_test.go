@@ -0,0 +1,176 @@
+package rustplus
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	goodPlayer uint64 = 1
+	badPlayer  uint64 = 2
+	busyPlayer uint64 = 3
+)
+
+func testInfo() *AppInfo {
+	return &AppInfo{
+		Name:          proto.String("Test Server"),
+		HeaderImage:   proto.String(""),
+		Url:           proto.String(""),
+		Map:           proto.String("Procedural Map"),
+		MapSize:       proto.Uint32(4000),
+		WipeTime:      proto.Uint32(1650000000),
+		Players:       proto.Uint32(10),
+		MaxPlayers:    proto.Uint32(100),
+		QueuedPlayers: proto.Uint32(0),
+	}
+}
+
+// Starts a websocket server that answers GetInfo: goodPlayer is accepted, badPlayer is rejected and busyPlayer is
+// rate limited. Other requests are never answered. Returns connection data pointing at it.
+func startInfoServer(t *testing.T) *ConnectionData {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			req := &AppRequest{}
+			if err := proto.Unmarshal(data, req); err != nil {
+				t.Errorf("server: %s", err)
+				return
+			}
+			if req.GetInfo == nil {
+				continue
+			}
+			r := &AppResponse{Seq: req.Seq}
+			switch req.GetPlayerId() {
+			case goodPlayer:
+				r.Info = testInfo()
+			case badPlayer:
+				r.Error = &AppError{Error: proto.String("invalid_playertoken")}
+			default:
+				r.Error = &AppError{Error: proto.String("rate_limit")}
+			}
+			data, _ = proto.Marshal(&AppMessage{Response: r})
+			if err := conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	p, _ := strconv.ParseUint(port, 10, 16)
+	data := NewConnectionData(host, p, false)
+	return &data
+}
+
+func handshakeClient(t *testing.T, players ...uint64) *Client {
+	data := startInfoServer(t)
+	for _, p := range players {
+		data.AddToken(PlayerToken{SteamId: p, Token: 1})
+	}
+	c := NewStandaloneClient(data)
+	c.VerifyTokens(true, time.Second)
+	return c
+}
+
+func TestHandshakeDisablesRejectedTokens(t *testing.T) {
+	c := handshakeClient(t, badPlayer, goodPlayer)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Disconnect()
+
+	tokens := c.Tokens()
+	var rejected *rejectedError
+	if !errors.As(tokens[0].Err, &rejected) || tokens[0].Verified {
+		t.Errorf("bad token: got %+v, want it rejected", tokens[0])
+	}
+	if tokens[1].Err != nil || !tokens[1].Verified {
+		t.Errorf("good token: got %+v, want it verified", tokens[1])
+	}
+	if c.ServerName() != "Test Server" {
+		t.Errorf("got server name %q, want %q", c.ServerName(), "Test Server")
+	}
+}
+
+func TestHandshakeNoValidTokens(t *testing.T) {
+	c := handshakeClient(t, badPlayer)
+	err := c.Connect()
+	var tokenErr *TokenError
+	if !errors.As(err, &tokenErr) || len(tokenErr.Tokens) != 1 {
+		t.Fatalf("got %v, want a TokenError for one token", err)
+	}
+}
+
+func TestHandshakeServerErrorKeepsToken(t *testing.T) {
+	c := handshakeClient(t, goodPlayer, busyPlayer)
+	err := c.Connect()
+	if err == nil || !strings.Contains(err.Error(), "rate_limit") {
+		t.Fatalf("got %v, want the rate limit error", err)
+	}
+	var tokenErr *TokenError
+	if errors.As(err, &tokenErr) {
+		t.Errorf("got a TokenError, want the handshake to give up")
+	}
+	for _, s := range c.Tokens() {
+		if s.Err != nil {
+			t.Errorf("token for %d was taken out of use: %s", s.Token.SteamId, s.Err)
+		}
+	}
+}
+
+func TestHandshakeWithReadLoop(t *testing.T) {
+	c := handshakeClient(t, goodPlayer)
+	// Run with -race: a read loop left running from an earlier connection must not read during the handshake.
+	stolen := make(chan *AppMessage, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			m, err := c.Read()
+			if err != nil {
+				runtime.Gosched()
+				continue
+			}
+			if m.GetResponse().GetInfo() != nil {
+				stolen <- m
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 5; i++ {
+		if err := c.Connect(); err != nil {
+			t.Fatal(err)
+		}
+		c.Disconnect()
+	}
+	select {
+	case m := <-stolen:
+		t.Errorf("read loop got the handshake reply %v", m)
+	default:
+	}
+}
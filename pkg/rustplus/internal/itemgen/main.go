@@ -0,0 +1,228 @@
+// Command itemgen builds data/items.json from the game's item definitions.
+//
+//	go run ./internal/itemgen -defs items -version 2022.01 -out data/items.json
+//
+// The definitions are either a directory of per item JSON files, as shipped in older server builds under
+// Bundles/items, or a single JSON array dumped from ItemManager.itemList. Each definition needs itemid and
+// shortname, and may have displayName (a string or {"english": ...}) or name, category (a name or the ItemCategory
+// number) and stackable. Aliases are not part of the game's data, so they are carried over from the existing table.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type item struct {
+	Id          int32    `json:"id"`
+	ShortName   string   `json:"shortName"`
+	DisplayName string   `json:"displayName"`
+	Category    string   `json:"category"`
+	StackSize   int32    `json:"stackSize"`
+	Aliases     []string `json:"aliases,omitempty"`
+}
+
+type itemFile struct {
+	Version string  `json:"version"`
+	Items   []*item `json:"items"`
+}
+
+// An item definition as the game stores it.
+type definition struct {
+	ItemId      *int32          `json:"itemid"`
+	ShortName   string          `json:"shortname"`
+	DisplayName json.RawMessage `json:"displayName"`
+	Name        string          `json:"name"`
+	Category    json.RawMessage `json:"category"`
+	Stackable   int32           `json:"stackable"`
+}
+
+// ItemCategory in the game's assembly, for dumps that store the category as a number.
+var categories = []string{
+	"Weapon", "Construction", "Items", "Resources", "Attire", "Tool", "Medical", "Food", "Ammunition", "Traps",
+	"Misc", "All", "Common", "Component", "Search", "Favourite", "Electrical", "Fun",
+}
+
+func main() {
+	defs := flag.String("defs", "", "directory of item definitions, or a JSON array of them")
+	out := flag.String("out", "data/items.json", "item table to write, aliases in it are kept")
+	version := flag.String("version", "", "version to record in the table, defaults to the existing one")
+	flag.Parse()
+	if *defs == "" {
+		log.Fatal("itemgen: -defs is required")
+	}
+
+	definitions, err := readDefinitions(*defs)
+	if err != nil {
+		log.Fatalf("itemgen: %s", err)
+	}
+	existing := &itemFile{}
+	if data, err := ioutil.ReadFile(*out); err == nil {
+		if err := json.Unmarshal(data, existing); err != nil {
+			log.Fatalf("itemgen: %s: %s", *out, err)
+		}
+	} else if !os.IsNotExist(err) {
+		log.Fatalf("itemgen: %s", err)
+	}
+	if *version == "" {
+		*version = existing.Version
+	}
+
+	items, err := buildItems(definitions, existing.Items)
+	if err != nil {
+		log.Fatalf("itemgen: %s", err)
+	}
+	if err := ioutil.WriteFile(*out, formatItems(*version, items), 0644); err != nil {
+		log.Fatalf("itemgen: %s", err)
+	}
+	log.Printf("itemgen: wrote %d items to %s", len(items), *out)
+}
+
+func readDefinitions(path string) ([]*definition, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		definitions := make([]*definition, 0)
+		if err := json.Unmarshal(data, &definitions); err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		return definitions, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(path, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	definitions := make([]*definition, 0, len(files))
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		d := &definition{}
+		if err := json.Unmarshal(data, d); err != nil {
+			return nil, fmt.Errorf("%s: %s", file, err)
+		}
+		definitions = append(definitions, d)
+	}
+	return definitions, nil
+}
+
+// Converts definitions to table entries sorted by short name, keeping the aliases of items already in the table.
+func buildItems(definitions []*definition, existing []*item) ([]*item, error) {
+	aliases := make(map[string][]string, len(existing))
+	for _, i := range existing {
+		aliases[i.ShortName] = i.Aliases
+	}
+
+	items := make([]*item, 0, len(definitions))
+	ids := make(map[int32]string, len(definitions))
+	for _, d := range definitions {
+		if d.ItemId == nil || d.ShortName == "" {
+			return nil, fmt.Errorf("definition %q has no item id or short name", d.ShortName)
+		}
+		if other, ok := ids[*d.ItemId]; ok {
+			return nil, fmt.Errorf("%s and %s share item id %d", other, d.ShortName, *d.ItemId)
+		}
+		ids[*d.ItemId] = d.ShortName
+
+		name, err := displayName(d)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", d.ShortName, err)
+		}
+		category, err := categoryName(d.Category)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", d.ShortName, err)
+		}
+		items = append(items, &item{
+			Id:          *d.ItemId,
+			ShortName:   d.ShortName,
+			DisplayName: name,
+			Category:    category,
+			StackSize:   d.Stackable,
+			Aliases:     aliases[d.ShortName],
+		})
+	}
+	sort.Slice(items, func(a, b int) bool {
+		return items[a].ShortName < items[b].ShortName
+	})
+	return items, nil
+}
+
+func displayName(d *definition) (string, error) {
+	if len(d.DisplayName) == 0 || string(d.DisplayName) == "null" {
+		if d.Name != "" {
+			return d.Name, nil
+		}
+		return d.ShortName, nil
+	}
+	var name string
+	if err := json.Unmarshal(d.DisplayName, &name); err == nil {
+		return name, nil
+	}
+	phrase := struct {
+		English string `json:"english"`
+	}{}
+	if err := json.Unmarshal(d.DisplayName, &phrase); err != nil {
+		return "", fmt.Errorf("display name: %s", err)
+	}
+	return phrase.English, nil
+}
+
+func categoryName(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	var name string
+	if err := json.Unmarshal(raw, &name); err == nil {
+		return name, nil
+	}
+	var n int
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return "", fmt.Errorf("category: %s", err)
+	}
+	if n < 0 || n >= len(categories) {
+		return "", fmt.Errorf("unknown category %d", n)
+	}
+	return categories[n], nil
+}
+
+// Writes the table one item per line, so updates diff cleanly.
+func formatItems(version string, items []*item) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "{\n  \"version\": %s,\n  \"items\": [\n", quote(version))
+	for n, i := range items {
+		line, _ := json.Marshal(i)
+		// Match the spacing of the hand written table.
+		line = bytes.ReplaceAll(line, []byte(`","`), []byte(`", "`))
+		line = bytes.ReplaceAll(line, []byte(`":`), []byte(`": `))
+		line = bytes.ReplaceAll(line, []byte(`,"`), []byte(`, "`))
+		b.WriteString("    ")
+		b.Write(line)
+		if n < len(items)-1 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteString("  ]\n}\n")
+	return b.Bytes()
+}
+
+func quote(s string) string {
+	data, _ := json.Marshal(s)
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,242 @@
+package rustplus
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// The item table shipped with the library. Load a newer one with LoadItemDatabase when Facepunch adds items, or
+// regenerate it from the game's item definitions with
+//
+//	RUST_ITEMS=path/to/Bundles/items go generate ./pkg/rustplus
+//
+//go:generate go run ./internal/itemgen -defs $RUST_ITEMS -out data/items.json
+//go:embed data/items.json
+var defaultItemsJson []byte
+
+// A Rust item, as referenced by AppEntityPayload_Item.ItemId and AppMarker_SellOrder.ItemId.
+type Item struct {
+	Id          int32    `json:"id"`
+	ShortName   string   `json:"shortName"`
+	DisplayName string   `json:"displayName"`
+	Category    string   `json:"category"`
+	StackSize   int32    `json:"stackSize"`
+	Aliases     []string `json:"aliases,omitempty"`
+}
+
+// A versioned table of items with lookups by id, short name and fuzzy search.
+type ItemDatabase struct {
+	Version     string
+	items       []*Item
+	byId        map[int32]*Item
+	byShortName map[string]*Item
+}
+
+type itemFile struct {
+	Version string  `json:"version"`
+	Items   []*Item `json:"items"`
+}
+
+// Parses an item table from JSON.
+func ParseItemDatabase(r io.Reader) (*ItemDatabase, error) {
+	file := itemFile{}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("item database: %s", err)
+	}
+	db := &ItemDatabase{
+		Version:     file.Version,
+		items:       make([]*Item, 0, len(file.Items)),
+		byId:        make(map[int32]*Item, len(file.Items)),
+		byShortName: make(map[string]*Item, len(file.Items)),
+	}
+	for i, item := range file.Items {
+		if item == nil || item.ShortName == "" {
+			return nil, fmt.Errorf("item database: item %d has no short name", i)
+		}
+		if _, ok := db.byId[item.Id]; ok {
+			return nil, fmt.Errorf("item database: duplicate item id %d", item.Id)
+		}
+		db.items = append(db.items, item)
+		db.byId[item.Id] = item
+		db.byShortName[strings.ToLower(item.ShortName)] = item
+	}
+	return db, nil
+}
+
+// Loads an item table from a JSON file.
+func LoadItemDatabase(path string) (*ItemDatabase, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseItemDatabase(f)
+}
+
+var (
+	itemDatabase     *ItemDatabase
+	itemDatabaseLock sync.RWMutex
+)
+
+// The item table used by the package level lookups. Defaults to the embedded table.
+func GetItemDatabase() *ItemDatabase {
+	itemDatabaseLock.RLock()
+	db := itemDatabase
+	itemDatabaseLock.RUnlock()
+	if db != nil {
+		return db
+	}
+
+	itemDatabaseLock.Lock()
+	defer itemDatabaseLock.Unlock()
+	if itemDatabase == nil {
+		db, err := ParseItemDatabase(bytes.NewReader(defaultItemsJson))
+		if err != nil {
+			panic(err)
+		}
+		itemDatabase = db
+	}
+	return itemDatabase
+}
+
+// Replaces the item table used by the package level lookups.
+func SetItemDatabase(db *ItemDatabase) {
+	itemDatabaseLock.Lock()
+	defer itemDatabaseLock.Unlock()
+	itemDatabase = db
+}
+
+// Looks up an item by id in the current item table.
+func GetItem(id int32) (*Item, bool) {
+	return GetItemDatabase().Get(id)
+}
+
+// The display name of an item, or a placeholder if it is not in the current item table.
+func ItemName(id int32) string {
+	if item, ok := GetItem(id); ok {
+		return item.DisplayName
+	}
+	return fmt.Sprintf("Unknown item (%d)", id)
+}
+
+// Searches the current item table. See ItemDatabase.Search.
+func SearchItems(query string, limit int) []*Item {
+	return GetItemDatabase().Search(query, limit)
+}
+
+// All items in the table.
+func (db *ItemDatabase) Items() []*Item {
+	return db.items
+}
+
+func (db *ItemDatabase) Get(id int32) (*Item, bool) {
+	item, ok := db.byId[id]
+	return item, ok
+}
+
+func (db *ItemDatabase) ByShortName(name string) (*Item, bool) {
+	item, ok := db.byShortName[strings.ToLower(name)]
+	return item, ok
+}
+
+// Finds items by name, best match first. Matches against display names, short names and aliases,
+// tolerating small typos. A limit of zero returns every match.
+func (db *ItemDatabase) Search(query string, limit int) []*Item {
+	q := normaliseItemName(query)
+	if q == "" {
+		return nil
+	}
+	type match struct {
+		item  *Item
+		score int
+	}
+	matches := make([]match, 0)
+	for _, item := range db.items {
+		best := -1
+		for _, name := range append([]string{item.DisplayName, item.ShortName}, item.Aliases...) {
+			if score := matchItemName(q, normaliseItemName(name)); score >= 0 && (best < 0 || score < best) {
+				best = score
+			}
+		}
+		if best >= 0 {
+			matches = append(matches, match{item, best})
+		}
+	}
+	sort.SliceStable(matches, func(a, b int) bool {
+		if matches[a].score != matches[b].score {
+			return matches[a].score < matches[b].score
+		}
+		return matches[a].item.DisplayName < matches[b].item.DisplayName
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	items := make([]*Item, len(matches))
+	for i, m := range matches {
+		items[i] = m.item
+	}
+	return items
+}
+
+// Scores how well a name matches the query, lower is better. Returns -1 for no match.
+func matchItemName(query, name string) int {
+	switch {
+	case name == query:
+		return 0
+	case strings.HasPrefix(name, query):
+		return 1
+	case strings.Contains(name, query):
+		return 2
+	}
+	// Allow roughly one typo for every four characters.
+	if d := levenshtein(query, name); d <= len(query)/4 {
+		return 3 + d
+	}
+	return -1
+}
+
+// Lower cases the name and strips anything that is not a letter or digit, so "LR-300" matches "lr300".
+func normaliseItemName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, minInt(current[j-1]+1, previous[j-1]+cost))
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
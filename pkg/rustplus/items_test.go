@@ -0,0 +1,84 @@
+package rustplus
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestItemDatabaseWellKnownIds(t *testing.T) {
+	tests := []struct {
+		id        int32
+		shortName string
+	}{
+		{-932201673, "scrap"},
+		{69511070, "metal.fragments"},
+		{317398316, "metal.refined"},
+		{-151838493, "wood"},
+		{-2099697608, "stones"},
+		{-1581843485, "sulfur"},
+		{-265876753, "gunpowder"},
+		{-592016202, "explosives"},
+		{73681876, "techparts"},
+		{1545779598, "rifle.ak"},
+		{-1812555177, "rifle.lr300"},
+		{1588298435, "rifle.bolt"},
+		{442886268, "rocket.launcher"},
+		{-742865266, "ammo.rocket.basic"},
+		{-1211166256, "ammo.rifle"},
+		{1248356124, "explosive.timed"},
+		{-1878475007, "explosive.satchel"},
+		{1079279582, "syringe.medical"},
+	}
+	db := GetItemDatabase()
+	for _, test := range tests {
+		item, ok := db.Get(test.id)
+		if !ok {
+			t.Errorf("item %d (%s) is missing", test.id, test.shortName)
+			continue
+		}
+		if item.ShortName != test.shortName {
+			t.Errorf("item %d is %s, want %s", test.id, item.ShortName, test.shortName)
+		}
+		if byName, ok := db.ByShortName(strings.ToUpper(test.shortName)); !ok || byName != item {
+			t.Errorf("ByShortName(%s) = %v, %t, want item %d", test.shortName, byName, ok, test.id)
+		}
+	}
+	if got := ItemName(ScrapItemId); got != "Scrap" {
+		t.Errorf("ItemName(ScrapItemId) = %q, want %q", got, "Scrap")
+	}
+}
+
+func TestItemDatabaseEntries(t *testing.T) {
+	db := GetItemDatabase()
+	shortNames := make(map[string]bool, len(db.Items()))
+	for _, item := range db.Items() {
+		if item.DisplayName == "" || item.Category == "" || item.StackSize <= 0 {
+			t.Errorf("item %d (%s) is missing fields: %+v", item.Id, item.ShortName, item)
+		}
+		if shortNames[item.ShortName] {
+			t.Errorf("short name %s is used twice", item.ShortName)
+		}
+		shortNames[item.ShortName] = true
+	}
+}
+
+func TestItemSearch(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"lr300", "rifle.lr300"},
+		{"hqm", "metal.refined"},
+		{"Assault Rifle", "rifle.ak"},
+		{"sulfer", "sulfur"},
+	}
+	for _, test := range tests {
+		items := SearchItems(test.query, 1)
+		if len(items) != 1 || items[0].ShortName != test.want {
+			t.Errorf("SearchItems(%q) = %v, want %s", test.query, items, test.want)
+		}
+	}
+	if items := SearchItems("zzzzzz", 0); len(items) != 0 {
+		t.Errorf("SearchItems(%q) = %v, want nothing", "zzzzzz", items)
+	}
+}
@@ -0,0 +1,141 @@
+package rustplus
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Width of a single grid square in world units, as drawn on the in-game map.
+const GridCellSize float32 = 146.3
+
+// World space bounds of a grid square.
+type GridBounds struct {
+	MinX, MinY float32
+	MaxX, MaxY float32
+}
+
+// Centre of the grid square.
+func (b GridBounds) Center() (float32, float32) {
+	return (b.MinX + b.MaxX) / 2, (b.MinY + b.MaxY) / 2
+}
+
+// Converts between world coordinates, grid references such as "G12" and pixels on the map image.
+// World coordinates start at the bottom left corner of the map, while grid rows start at the top.
+type MapGrid struct {
+	MapSize float32
+
+	// Map image dimensions. These are only needed for pixel conversion.
+	Width       uint32
+	Height      uint32
+	OceanMargin int32
+}
+
+// Creates a grid for a map of the given size. Pixel conversion is unavailable until SetImage is called.
+func NewMapGrid(mapSize uint32) *MapGrid {
+	return &MapGrid{MapSize: float32(mapSize)}
+}
+
+// Creates a grid from a GetInfo response, along with a GetMap response if pixel conversion is needed.
+func NewMapGridFromInfo(info *AppInfo, m *AppMap) *MapGrid {
+	g := NewMapGrid(info.GetMapSize())
+	if m != nil {
+		g.SetImage(m)
+	}
+	return g
+}
+
+// Sets the image dimensions used for pixel conversion.
+func (g *MapGrid) SetImage(m *AppMap) {
+	g.Width = m.GetWidth()
+	g.Height = m.GetHeight()
+	g.OceanMargin = m.GetOceanMargin()
+}
+
+// Number of grid squares along each side of the map.
+func (g *MapGrid) Cells() int {
+	return int(g.MapSize / GridCellSize)
+}
+
+// The grid reference for the given world position, e.g. "G12". Positions off the grid are clamped to the nearest square.
+func (g *MapGrid) Grid(x, y float32) string {
+	column, row := g.Cell(x, y)
+	return GridColumnName(column) + strconv.Itoa(row)
+}
+
+// The column and row of the grid square containing the given world position.
+func (g *MapGrid) Cell(x, y float32) (int, int) {
+	return g.clamp(int(math.Floor(float64(x / GridCellSize)))), g.clamp(int(math.Floor(float64((g.MapSize - y) / GridCellSize))))
+}
+
+func (g *MapGrid) clamp(i int) int {
+	if i < 0 {
+		return 0
+	}
+	if cells := g.Cells(); i >= cells && cells > 0 {
+		return cells - 1
+	}
+	return i
+}
+
+// The world space bounds of a grid reference such as "G12".
+func (g *MapGrid) Bounds(grid string) (GridBounds, error) {
+	column, row, err := ParseGrid(grid)
+	if err != nil {
+		return GridBounds{}, err
+	}
+	if column >= g.Cells() || row >= g.Cells() {
+		return GridBounds{}, fmt.Errorf("grid %s is off the map", grid)
+	}
+	maxY := g.MapSize - float32(row)*GridCellSize
+	return GridBounds{
+		MinX: float32(column) * GridCellSize,
+		MinY: maxY - GridCellSize,
+		MaxX: float32(column+1) * GridCellSize,
+		MaxY: maxY,
+	}, nil
+}
+
+// The pixel position of a world position on the map image.
+func (g *MapGrid) Pixel(x, y float32) (int, int, error) {
+	if g.Width == 0 || g.Height == 0 {
+		return 0, 0, fmt.Errorf("map image dimensions are not set")
+	}
+	if g.MapSize == 0 {
+		return 0, 0, fmt.Errorf("map size is not set")
+	}
+	margin := float32(g.OceanMargin)
+	px := x/g.MapSize*(float32(g.Width)-2*margin) + margin
+	py := float32(g.Height) - (y/g.MapSize*(float32(g.Height)-2*margin) + margin)
+	return int(px), int(py), nil
+}
+
+// The column letters for a zero based column index: 0 is "A", 25 is "Z" and 26 is "AA".
+func GridColumnName(column int) string {
+	name := ""
+	for column >= 0 {
+		name = string(rune('A'+column%26)) + name
+		column = column/26 - 1
+	}
+	return name
+}
+
+// Splits a grid reference such as "G12" into a zero based column and row.
+func ParseGrid(grid string) (int, int, error) {
+	grid = strings.ToUpper(strings.TrimSpace(grid))
+	i := 0
+	column := 0
+	for i < len(grid) && grid[i] >= 'A' && grid[i] <= 'Z' {
+		column = column*26 + int(grid[i]-'A'+1)
+		i++
+	}
+	if i == 0 || i == len(grid) {
+		return 0, 0, fmt.Errorf("invalid grid reference: %q", grid)
+	}
+	row, err := strconv.Atoi(grid[i:])
+	if err != nil || row < 0 {
+		return 0, 0, fmt.Errorf("invalid grid reference: %q", grid)
+	}
+	return column - 1, row, nil
+}
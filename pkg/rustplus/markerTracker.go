@@ -0,0 +1,286 @@
+package rustplus
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+type MarkerEventKind int
+
+const (
+	MarkerSpawned MarkerEventKind = iota
+	MarkerDespawned
+)
+
+// Names used when describing world events.
+var markerEventNames = map[AppMarkerType][2]string{
+	AppMarkerType_CargoShip:     {"Cargo ship entered the map", "Cargo ship left the map"},
+	AppMarkerType_CH47:          {"Chinook arrived", "Chinook left"},
+	AppMarkerType_Crate:         {"Locked crate dropped", "Locked crate gone"},
+	AppMarkerType_Explosion:     {"Explosion spotted", "Explosion faded"},
+	AppMarkerType_GenericRadius: {"Event started", "Event ended"},
+}
+
+// A marker appearing on, or disappearing from, the map.
+type MarkerEvent struct {
+	Kind   MarkerEventKind
+	Marker *AppMarker
+	Time   time.Time
+	// Grid reference of the marker. Empty unless the tracker has a grid.
+	Grid string
+	// Closest monument to the marker and its distance. Nil unless the tracker has a monument index.
+	Monument         *Monument
+	MonumentDistance float32
+}
+
+func (e *MarkerEvent) Type() AppMarkerType {
+	return e.Marker.GetType()
+}
+
+// Describes the event, e.g. "Cargo ship entered the map at G12 near Harbor".
+func (e *MarkerEvent) String() string {
+	s := fmt.Sprintf("%s marker %d", e.Type(), e.Marker.GetId())
+	if names, ok := markerEventNames[e.Type()]; ok {
+		s = names[e.Kind]
+	}
+	if e.Grid != "" {
+		s += " at " + e.Grid
+	}
+	if e.Monument != nil {
+		s += " near " + e.Monument.Name
+	}
+	return s
+}
+
+type MarkerEventFunc func(e *MarkerEvent)
+
+type MarkerUpdateFunc func(m *AppMapMarkers)
+
+// Polls the map markers and reports world events as markers come and go.
+type MarkerTracker struct {
+	client      *Client
+	lock        sync.Mutex
+	markers     map[uint32]*AppMarker
+	paths       map[uint32]*Trajectory
+	initialised bool
+	handlers    map[uint32]MarkerEventFunc
+	updates     map[uint32]MarkerUpdateFunc
+	hSeq        uint32
+	quit        chan struct{}
+	once        sync.Once
+
+	// Optional helpers used to describe where events happen.
+	Grid      *MapGrid
+	Monuments *MonumentIndex
+	// Marker types that raise events. Defaults to the world events: cargo, chinook, crates, explosions and generic radius events.
+	Types map[AppMarkerType]bool
+	// Marker types whose movement is recorded. Defaults to cargo ships and chinooks.
+	Moving map[AppMarkerType]bool
+	// Number of positions kept for each moving marker.
+	TrajectoryLength int
+}
+
+func NewMarkerTracker(c *Client) *MarkerTracker {
+	return &MarkerTracker{
+		client:   c,
+		markers:  make(map[uint32]*AppMarker),
+		paths:    make(map[uint32]*Trajectory),
+		handlers: make(map[uint32]MarkerEventFunc),
+		updates:  make(map[uint32]MarkerUpdateFunc),
+		quit:     make(chan struct{}),
+		Types: map[AppMarkerType]bool{
+			AppMarkerType_CargoShip:     true,
+			AppMarkerType_CH47:          true,
+			AppMarkerType_Crate:         true,
+			AppMarkerType_Explosion:     true,
+			AppMarkerType_GenericRadius: true,
+		},
+		Moving: map[AppMarkerType]bool{
+			AppMarkerType_CargoShip: true,
+			AppMarkerType_CH47:      true,
+		},
+		TrajectoryLength: DefaultTrajectoryLength,
+	}
+}
+
+// Registers a function to be called for each marker event.
+func (t *MarkerTracker) AddEventHandler(f MarkerEventFunc) uint32 {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.hSeq++
+	t.handlers[t.hSeq] = f
+	return t.hSeq
+}
+
+// Removes an event handler.
+func (t *MarkerTracker) RemoveEventHandler(i uint32) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if _, ok := t.handlers[i]; ok {
+		delete(t.handlers, i)
+		return nil
+	}
+	return fmt.Errorf("event id %d is not registered", i)
+}
+
+// Registers a function to be called with every raw marker update, after events have been raised.
+func (t *MarkerTracker) AddUpdateHandler(f MarkerUpdateFunc) uint32 {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.hSeq++
+	t.updates[t.hSeq] = f
+	return t.hSeq
+}
+
+// Removes an update handler.
+func (t *MarkerTracker) RemoveUpdateHandler(i uint32) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if _, ok := t.updates[i]; ok {
+		delete(t.updates, i)
+		return nil
+	}
+	return fmt.Errorf("update id %d is not registered", i)
+}
+
+// Starts polling the markers in the background.
+func (t *MarkerTracker) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		t.Poll()
+		for {
+			select {
+			case <-t.quit:
+				return
+			case <-ticker.C:
+				t.Poll()
+			}
+		}
+	}()
+}
+
+// Stops polling.
+func (t *MarkerTracker) Stop() {
+	t.once.Do(func() {
+		close(t.quit)
+	})
+}
+
+// Requests the markers once. The tracker is updated when the response arrives.
+func (t *MarkerTracker) Poll() error {
+	return t.client.GetMapMarkers(t.Update)
+}
+
+// Diffs a GetMapMarkers response against the previous one, raising events for any changes.
+// The first update only records the markers, so nothing is reported for events already in progress.
+func (t *MarkerTracker) Update(m *AppMapMarkers) {
+	now := time.Now()
+	t.lock.Lock()
+	current := make(map[uint32]*AppMarker, len(m.GetMarkers()))
+	for _, marker := range m.GetMarkers() {
+		current[marker.GetId()] = marker
+	}
+	events := make([]*MarkerEvent, 0)
+	if t.initialised {
+		for id, marker := range current {
+			if _, ok := t.markers[id]; !ok && t.Types[marker.GetType()] {
+				events = append(events, t.newEvent(MarkerSpawned, marker, now))
+			}
+		}
+		for id, marker := range t.markers {
+			if _, ok := current[id]; !ok && t.Types[marker.GetType()] {
+				events = append(events, t.newEvent(MarkerDespawned, marker, now))
+			}
+		}
+	}
+	t.markers = current
+	t.initialised = true
+	t.updatePaths(now)
+	handlers := make([]MarkerEventFunc, 0, len(t.handlers))
+	for _, f := range t.handlers {
+		handlers = append(handlers, f)
+	}
+	updates := make([]MarkerUpdateFunc, 0, len(t.updates))
+	for _, f := range t.updates {
+		updates = append(updates, f)
+	}
+	t.lock.Unlock()
+
+	for _, e := range events {
+		for _, f := range handlers {
+			f(e)
+		}
+	}
+	for _, f := range updates {
+		f(m)
+	}
+}
+
+// Records the latest position of each moving marker, forgetting any that have gone.
+func (t *MarkerTracker) updatePaths(now time.Time) {
+	for id := range t.paths {
+		if _, ok := t.markers[id]; !ok {
+			delete(t.paths, id)
+		}
+	}
+	for id, marker := range t.markers {
+		if !t.Moving[marker.GetType()] {
+			continue
+		}
+		path, ok := t.paths[id]
+		if !ok {
+			path = &Trajectory{Id: id, Type: marker.GetType()}
+			t.paths[id] = path
+		}
+		path.add(marker, now, t.TrajectoryLength)
+	}
+}
+
+func (t *MarkerTracker) newEvent(kind MarkerEventKind, marker *AppMarker, now time.Time) *MarkerEvent {
+	e := &MarkerEvent{Kind: kind, Marker: marker, Time: now}
+	if t.Grid != nil {
+		e.Grid = t.Grid.Grid(marker.GetX(), marker.GetY())
+	}
+	if t.Monuments != nil {
+		e.Monument, e.MonumentDistance = t.Monuments.NearestMonument(marker.GetX(), marker.GetY())
+	}
+	return e
+}
+
+// All markers of the given type from the latest update.
+func (t *MarkerTracker) Markers(markerType AppMarkerType) []*AppMarker {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	markers := make([]*AppMarker, 0)
+	for _, m := range t.markers {
+		if m.GetType() == markerType {
+			markers = append(markers, m)
+		}
+	}
+	return markers
+}
+
+// A snapshot of the recorded trajectory for a moving marker.
+func (t *MarkerTracker) Trajectory(id uint32) (*Trajectory, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if path, ok := t.paths[id]; ok {
+		return path.copy(), true
+	}
+	return nil, false
+}
+
+// Snapshots of the recorded trajectories for all moving markers of the given type.
+func (t *MarkerTracker) Trajectories(markerType AppMarkerType) []*Trajectory {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	paths := make([]*Trajectory, 0)
+	for _, path := range t.paths {
+		if path.Type == markerType {
+			paths = append(paths, path.copy())
+		}
+	}
+	return paths
+}
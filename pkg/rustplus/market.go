@@ -0,0 +1,230 @@
+package rustplus
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Item id of scrap, the usual vending machine currency.
+const ScrapItemId int32 = -932201673
+
+// A single sell order on a vending machine.
+type Listing struct {
+	VendorId   uint32
+	VendorName string
+	X          float32
+	Y          float32
+	Order      *AppMarker_SellOrder
+}
+
+// Cost of a single item, in the order's currency.
+func (l *Listing) UnitPrice() float32 {
+	if l.Order.GetQuantity() == 0 {
+		return 0
+	}
+	return float32(l.Order.GetCostPerItem()) / float32(l.Order.GetQuantity())
+}
+
+// True if the vendor has at least one order in stock.
+func (l *Listing) InStock() bool {
+	return l.Order.GetAmountInStock() > 0
+}
+
+// Identifies a listing across polls. Vendors can sell the same item several times, for different currencies,
+// quantities or prices, and can even repeat an identical order, which n tells apart.
+type listingKey struct {
+	vendor     uint32
+	item       int32
+	quantity   int32
+	currency   int32
+	cost       int32
+	blueprint  bool
+	currencyBp bool
+	n          int
+}
+
+func newListingKey(l *Listing, n int) listingKey {
+	o := l.Order
+	return listingKey{
+		vendor:     l.VendorId,
+		item:       o.GetItemId(),
+		quantity:   o.GetQuantity(),
+		currency:   o.GetCurrencyId(),
+		cost:       o.GetCostPerItem(),
+		blueprint:  o.GetItemIsBlueprint(),
+		currencyBp: o.GetCurrencyIsBlueprint(),
+		n:          n,
+	}
+}
+
+type MarketEventKind int
+
+const (
+	// An item is for sale that was not sold anywhere before.
+	MarketItemListed MarketEventKind = iota
+	// A vendor has started selling an item.
+	MarketListingAdded
+	// A vendor has stopped selling an item.
+	MarketListingRemoved
+	// The stock of a listing has changed.
+	MarketStockChanged
+)
+
+type MarketEvent struct {
+	Kind    MarketEventKind
+	Listing *Listing
+	// Stock before the change. Only set for MarketStockChanged.
+	PreviousStock int32
+}
+
+type MarketEventFunc func(e *MarketEvent)
+
+// Indexes the sell orders of every vending machine on the map. Feed it marker updates, either directly
+// or by attaching it to a MarkerTracker.
+type MarketIndex struct {
+	lock        sync.RWMutex
+	listings    map[listingKey]*Listing
+	items       map[int32][]*Listing
+	initialised bool
+	handlers    map[uint32]MarketEventFunc
+	hSeq        uint32
+}
+
+func NewMarketIndex() *MarketIndex {
+	return &MarketIndex{
+		listings: make(map[listingKey]*Listing),
+		items:    make(map[int32][]*Listing),
+		handlers: make(map[uint32]MarketEventFunc),
+	}
+}
+
+// Keeps the index up to date with every poll made by the tracker.
+func (i *MarketIndex) Attach(t *MarkerTracker) uint32 {
+	return t.AddUpdateHandler(i.Update)
+}
+
+// Registers a function to be called for each market event.
+func (i *MarketIndex) AddEventHandler(f MarketEventFunc) uint32 {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.hSeq++
+	i.handlers[i.hSeq] = f
+	return i.hSeq
+}
+
+// Removes an event handler.
+func (i *MarketIndex) RemoveEventHandler(id uint32) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if _, ok := i.handlers[id]; ok {
+		delete(i.handlers, id)
+		return nil
+	}
+	return fmt.Errorf("event id %d is not registered", id)
+}
+
+// Rebuilds the index from a GetMapMarkers response, raising events for anything that changed.
+// The first update only records the listings.
+func (i *MarketIndex) Update(m *AppMapMarkers) {
+	listings := make(map[listingKey]*Listing)
+	items := make(map[int32][]*Listing)
+	for _, marker := range m.GetMarkers() {
+		if marker.GetType() != AppMarkerType_VendingMachine {
+			continue
+		}
+		repeats := make(map[listingKey]int)
+		for _, order := range marker.GetSellOrders() {
+			l := &Listing{
+				VendorId:   marker.GetId(),
+				VendorName: marker.GetName(),
+				X:          marker.GetX(),
+				Y:          marker.GetY(),
+				Order:      order,
+			}
+			key := newListingKey(l, 0)
+			n := repeats[key]
+			repeats[key]++
+			listings[newListingKey(l, n)] = l
+			items[order.GetItemId()] = append(items[order.GetItemId()], l)
+		}
+	}
+
+	i.lock.Lock()
+	events := make([]*MarketEvent, 0)
+	if i.initialised {
+		for key, l := range listings {
+			previous, ok := i.listings[key]
+			switch {
+			case !ok && len(i.items[key.item]) == 0:
+				events = append(events, &MarketEvent{Kind: MarketItemListed, Listing: l})
+			case !ok:
+				events = append(events, &MarketEvent{Kind: MarketListingAdded, Listing: l})
+			case previous.Order.GetAmountInStock() != l.Order.GetAmountInStock():
+				events = append(events, &MarketEvent{Kind: MarketStockChanged, Listing: l, PreviousStock: previous.Order.GetAmountInStock()})
+			}
+		}
+		for key, l := range i.listings {
+			if _, ok := listings[key]; !ok {
+				events = append(events, &MarketEvent{Kind: MarketListingRemoved, Listing: l})
+			}
+		}
+	}
+	i.listings = listings
+	i.items = items
+	i.initialised = true
+	handlers := make([]MarketEventFunc, 0, len(i.handlers))
+	for _, f := range i.handlers {
+		handlers = append(handlers, f)
+	}
+	i.lock.Unlock()
+
+	for _, e := range events {
+		for _, f := range handlers {
+			f(e)
+		}
+	}
+}
+
+// Every listing for the item, grouped by currency with scrap first, and cheapest first within each currency.
+// Prices in different currencies cannot be compared, so use Cheapest to pick a seller for a given currency.
+// Includes listings that are out of stock.
+func (i *MarketIndex) Sellers(itemId int32) []*Listing {
+	i.lock.RLock()
+	listings := make([]*Listing, len(i.items[itemId]))
+	copy(listings, i.items[itemId])
+	i.lock.RUnlock()
+
+	sort.SliceStable(listings, func(a, b int) bool {
+		ca, cb := listings[a].Order.GetCurrencyId(), listings[b].Order.GetCurrencyId()
+		if ca != cb {
+			if ca == ScrapItemId || cb == ScrapItemId {
+				return ca == ScrapItemId
+			}
+			return ca < cb
+		}
+		return listings[a].UnitPrice() < listings[b].UnitPrice()
+	})
+	return listings
+}
+
+// The cheapest in-stock listing for the item, paid for with the given currency.
+func (i *MarketIndex) Cheapest(itemId int32, currencyId int32) (*Listing, bool) {
+	for _, l := range i.Sellers(itemId) {
+		if l.InStock() && l.Order.GetCurrencyId() == currencyId {
+			return l, true
+		}
+	}
+	return nil, false
+}
+
+// The ids of every item currently listed.
+func (i *MarketIndex) Items() []int32 {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	items := make([]int32, 0, len(i.items))
+	for id := range i.items {
+		items = append(items, id)
+	}
+	return items
+}
@@ -0,0 +1,152 @@
+package rustplus
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const woodItemId int32 = -151838493
+
+func sellOrder(item, quantity, currency, cost, stock int32) *AppMarker_SellOrder {
+	return &AppMarker_SellOrder{
+		ItemId:              proto.Int32(item),
+		Quantity:            proto.Int32(quantity),
+		CurrencyId:          proto.Int32(currency),
+		CostPerItem:         proto.Int32(cost),
+		AmountInStock:       proto.Int32(stock),
+		ItemIsBlueprint:     proto.Bool(false),
+		CurrencyIsBlueprint: proto.Bool(false),
+	}
+}
+
+func vendingMachine(id uint32, orders ...*AppMarker_SellOrder) *AppMarker {
+	return &AppMarker{
+		Id:         proto.Uint32(id),
+		Type:       AppMarkerType_VendingMachine.Enum(),
+		X:          proto.Float32(100),
+		Y:          proto.Float32(100),
+		Name:       proto.String("shop"),
+		SellOrders: orders,
+	}
+}
+
+func markers(m ...*AppMarker) *AppMapMarkers {
+	return &AppMapMarkers{Markers: m}
+}
+
+func TestMarketIndexRepeatedOrders(t *testing.T) {
+	index := NewMarketIndex()
+	events := make([]*MarketEvent, 0)
+	index.AddEventHandler(func(e *MarketEvent) { events = append(events, e) })
+
+	// Two orders for the same item and currency, differing only by quantity, plus an exact repeat.
+	index.Update(markers(vendingMachine(1,
+		sellOrder(woodItemId, 1000, ScrapItemId, 10, 5),
+		sellOrder(woodItemId, 5000, ScrapItemId, 40, 5),
+		sellOrder(woodItemId, 5000, ScrapItemId, 40, 5),
+	)))
+	if got := len(index.Sellers(woodItemId)); got != 3 {
+		t.Fatalf("Sellers returned %d listings, want 3", got)
+	}
+	if got := len(index.listings); got != 3 {
+		t.Fatalf("index kept %d listings, want 3", got)
+	}
+
+	index.Update(markers(vendingMachine(1,
+		sellOrder(woodItemId, 1000, ScrapItemId, 10, 5),
+		sellOrder(woodItemId, 5000, ScrapItemId, 40, 5),
+		sellOrder(woodItemId, 5000, ScrapItemId, 40, 2),
+	)))
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	e := events[0]
+	if e.Kind != MarketStockChanged || e.PreviousStock != 5 || e.Listing.Order.GetAmountInStock() != 2 {
+		t.Errorf("got event %+v, want stock change from 5 to 2", e)
+	}
+}
+
+func TestMarketIndexEvents(t *testing.T) {
+	tests := []struct {
+		name   string
+		before *AppMapMarkers
+		after  *AppMapMarkers
+		want   []MarketEventKind
+	}{
+		{
+			name:   "new item",
+			before: markers(vendingMachine(1)),
+			after:  markers(vendingMachine(1, sellOrder(woodItemId, 1000, ScrapItemId, 10, 1))),
+			want:   []MarketEventKind{MarketItemListed},
+		},
+		{
+			name:   "another vendor",
+			before: markers(vendingMachine(1, sellOrder(woodItemId, 1000, ScrapItemId, 10, 1))),
+			after: markers(
+				vendingMachine(1, sellOrder(woodItemId, 1000, ScrapItemId, 10, 1)),
+				vendingMachine(2, sellOrder(woodItemId, 1000, ScrapItemId, 12, 1)),
+			),
+			want: []MarketEventKind{MarketListingAdded},
+		},
+		{
+			name:   "removed",
+			before: markers(vendingMachine(1, sellOrder(woodItemId, 1000, ScrapItemId, 10, 1))),
+			after:  markers(vendingMachine(1)),
+			want:   []MarketEventKind{MarketListingRemoved},
+		},
+		{
+			name:   "price change replaces the listing",
+			before: markers(vendingMachine(1, sellOrder(woodItemId, 1000, ScrapItemId, 10, 1))),
+			after:  markers(vendingMachine(1, sellOrder(woodItemId, 1000, ScrapItemId, 20, 1))),
+			want:   []MarketEventKind{MarketListingAdded, MarketListingRemoved},
+		},
+		{
+			name:   "unchanged",
+			before: markers(vendingMachine(1, sellOrder(woodItemId, 1000, ScrapItemId, 10, 1))),
+			after:  markers(vendingMachine(1, sellOrder(woodItemId, 1000, ScrapItemId, 10, 1))),
+			want:   []MarketEventKind{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			index := NewMarketIndex()
+			index.Update(test.before)
+			got := make([]MarketEventKind, 0)
+			index.AddEventHandler(func(e *MarketEvent) { got = append(got, e.Kind) })
+			index.Update(test.after)
+			if len(got) != len(test.want) {
+				t.Fatalf("got events %v, want %v", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Fatalf("got events %v, want %v", got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMarketIndexSellersByCurrency(t *testing.T) {
+	const metalItemId int32 = 69511070
+	index := NewMarketIndex()
+	index.Update(markers(
+		vendingMachine(1, sellOrder(woodItemId, 1000, metalItemId, 1, 1)),
+		vendingMachine(2, sellOrder(woodItemId, 1000, ScrapItemId, 30, 1)),
+		vendingMachine(3, sellOrder(woodItemId, 1000, ScrapItemId, 20, 1)),
+	))
+	got := make([]uint32, 0)
+	for _, l := range index.Sellers(woodItemId) {
+		got = append(got, l.VendorId)
+	}
+	want := []uint32{3, 2, 1}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got vendors %v, want %v", got, want)
+		}
+	}
+
+	if l, ok := index.Cheapest(woodItemId, metalItemId); !ok || l.VendorId != 1 {
+		t.Errorf("Cheapest in metal = %v, %t, want vendor 1", l, ok)
+	}
+}
@@ -0,0 +1,159 @@
+package rustplus
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// Broad grouping of monuments, handy for filtering.
+type MonumentCategory string
+
+const (
+	MonumentLarge    MonumentCategory = "large"
+	MonumentSmall    MonumentCategory = "small"
+	MonumentSafe     MonumentCategory = "safezone"
+	MonumentOffshore MonumentCategory = "offshore"
+	MonumentRoadside MonumentCategory = "roadside"
+	MonumentQuarry   MonumentCategory = "quarry"
+	MonumentTunnel   MonumentCategory = "tunnel"
+	MonumentOther    MonumentCategory = "other"
+)
+
+// Human readable details for a monument token.
+type MonumentInfo struct {
+	Name     string
+	Category MonumentCategory
+}
+
+// Known monument tokens, as found in AppMap_Monument.Token.
+var MonumentCatalogue = map[string]MonumentInfo{
+	"abandonedmilitarybase":              {"Abandoned Military Base", MonumentLarge},
+	"airfield_display_name":              {"Airfield", MonumentLarge},
+	"arctic_base_a":                      {"Arctic Research Base", MonumentLarge},
+	"bandit_camp":                        {"Bandit Camp", MonumentSafe},
+	"dome_monument_name":                 {"The Dome", MonumentSmall},
+	"excavator":                          {"Giant Excavator Pit", MonumentLarge},
+	"ferryterminal":                      {"Ferry Terminal", MonumentLarge},
+	"fishing_village_display_name":       {"Fishing Village", MonumentSafe},
+	"gas_station":                        {"Oxum's Gas Station", MonumentRoadside},
+	"harbor_display_name":                {"Harbor", MonumentLarge},
+	"harbor_2_display_name":              {"Harbor", MonumentLarge},
+	"junkyard_display_name":              {"Junkyard", MonumentLarge},
+	"large_fishing_village_display_name": {"Large Fishing Village", MonumentSafe},
+	"large_oil_rig":                      {"Large Oil Rig", MonumentOffshore},
+	"launchsite":                         {"Launch Site", MonumentLarge},
+	"lighthouse_display_name":            {"Lighthouse", MonumentSmall},
+	"military_tunnels_display_name":      {"Military Tunnel", MonumentLarge},
+	"mining_outpost_display_name":        {"Mining Outpost", MonumentRoadside},
+	"mining_quarry_hqm_display_name":     {"HQM Quarry", MonumentQuarry},
+	"mining_quarry_stone_display_name":   {"Stone Quarry", MonumentQuarry},
+	"mining_quarry_sulfur_display_name":  {"Sulfur Quarry", MonumentQuarry},
+	"missile_silo_monument":              {"Missile Silo", MonumentLarge},
+	"oil_rig_small":                      {"Oil Rig", MonumentOffshore},
+	"outpost":                            {"Outpost", MonumentSafe},
+	"power_plant_display_name":           {"Power Plant", MonumentLarge},
+	"satellite_dish_display_name":        {"Satellite Dish", MonumentSmall},
+	"sewer_display_name":                 {"Sewer Branch", MonumentSmall},
+	"stables_a":                          {"Ranch", MonumentSafe},
+	"stables_b":                          {"Large Barn", MonumentSafe},
+	"supermarket":                        {"Abandoned Supermarket", MonumentRoadside},
+	"swamp_c":                            {"Abandoned Cabins", MonumentOther},
+	"train_tunnel_display_name":          {"Train Tunnel", MonumentTunnel},
+	"train_tunnel_link_display_name":     {"Train Tunnel Link", MonumentTunnel},
+	"train_yard_display_name":            {"Train Yard", MonumentLarge},
+	"underwater_lab":                     {"Underwater Lab", MonumentOffshore},
+	"water_treatment_plant_display_name": {"Water Treatment Plant", MonumentLarge},
+}
+
+// Looks up the details for a monument token. Unknown tokens get a name derived from the token itself.
+func LookupMonument(token string) (MonumentInfo, bool) {
+	if info, ok := MonumentCatalogue[strings.ToLower(token)]; ok {
+		return info, true
+	}
+	name := strings.TrimSuffix(strings.ToLower(token), "_display_name")
+	name = strings.TrimSuffix(name, "_monument_name")
+	words := strings.Fields(strings.ReplaceAll(name, "_", " "))
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return MonumentInfo{Name: strings.Join(words, " "), Category: MonumentOther}, false
+}
+
+// A monument on the current map.
+type Monument struct {
+	MonumentInfo
+	Token string
+	X     float32
+	Y     float32
+}
+
+// Distance from the monument to a world position.
+func (m *Monument) Distance(x, y float32) float32 {
+	return float32(math.Hypot(float64(m.X-x), float64(m.Y-y)))
+}
+
+// The monuments on a map, with nearest-monument queries.
+type MonumentIndex struct {
+	monuments []*Monument
+}
+
+// Builds an index from a GetMap response.
+func NewMonumentIndex(m *AppMap) *MonumentIndex {
+	index := &MonumentIndex{monuments: make([]*Monument, 0, len(m.GetMonuments()))}
+	for _, mon := range m.GetMonuments() {
+		info, _ := LookupMonument(mon.GetToken())
+		index.monuments = append(index.monuments, &Monument{
+			MonumentInfo: info,
+			Token:        mon.GetToken(),
+			X:            mon.GetX(),
+			Y:            mon.GetY(),
+		})
+	}
+	return index
+}
+
+// All monuments on the map.
+func (i *MonumentIndex) Monuments() []*Monument {
+	return i.monuments
+}
+
+// The monument closest to a world position and its distance. Returns nil if the map has no monuments.
+// Train tunnel entrances are skipped, as they are rarely a useful landmark.
+func (i *MonumentIndex) NearestMonument(x, y float32) (*Monument, float32) {
+	var nearest *Monument
+	best := float32(math.MaxFloat32)
+	for _, m := range i.monuments {
+		if m.Category == MonumentTunnel {
+			continue
+		}
+		if d := m.Distance(x, y); d < best {
+			nearest, best = m, d
+		}
+	}
+	return nearest, best
+}
+
+// All monuments within the radius of a world position, closest first.
+func (i *MonumentIndex) Within(x, y, radius float32) []*Monument {
+	found := make([]*Monument, 0)
+	for _, m := range i.monuments {
+		if m.Distance(x, y) <= radius {
+			found = append(found, m)
+		}
+	}
+	sort.Slice(found, func(a, b int) bool {
+		return found[a].Distance(x, y) < found[b].Distance(x, y)
+	})
+	return found
+}
+
+// True if the world position is within the radius of a monument with the given token.
+func (i *MonumentIndex) IsNear(token string, x, y, radius float32) bool {
+	for _, m := range i.monuments {
+		if strings.EqualFold(m.Token, token) && m.Distance(x, y) <= radius {
+			return true
+		}
+	}
+	return false
+}
@@ -1,7 +1,17 @@
 package rustplus
 
+import "fmt"
+
 type PlayerToken struct {
 	Name    string
 	SteamId uint64
 	Token   int32
 }
+
+// Identifies the player without revealing the token.
+func (t PlayerToken) String() string {
+	if t.Name != "" {
+		return fmt.Sprintf("%s (%d)", t.Name, t.SteamId)
+	}
+	return fmt.Sprint(t.SteamId)
+}
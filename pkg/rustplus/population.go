@@ -0,0 +1,269 @@
+package rustplus
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Default number of samples kept by a PopulationTracker, a day's worth at one poll per minute.
+const DefaultPopulationHistory = 24 * 60
+
+// Server population at a point in time.
+type PopulationSample struct {
+	Time       time.Time
+	Players    uint32
+	MaxPlayers uint32
+	Queued     uint32
+}
+
+func newPopulationSample(info *AppInfo, now time.Time) PopulationSample {
+	return PopulationSample{
+		Time:       now,
+		Players:    info.GetPlayers(),
+		MaxPlayers: info.GetMaxPlayers(),
+		Queued:     info.GetQueuedPlayers(),
+	}
+}
+
+// Somewhere to keep population history. Implement this to persist samples to disk or a database.
+type PopulationStore interface {
+	Add(s PopulationSample)
+	// All samples taken at or after the given time, oldest first.
+	Samples(since time.Time) []PopulationSample
+}
+
+// An in-memory PopulationStore that keeps a fixed number of the most recent samples.
+type PopulationRing struct {
+	lock    sync.RWMutex
+	samples []PopulationSample
+	next    int
+	full    bool
+}
+
+func NewPopulationRing(size int) *PopulationRing {
+	if size < 1 {
+		size = 1
+	}
+	return &PopulationRing{samples: make([]PopulationSample, size)}
+}
+
+func (r *PopulationRing) Add(s PopulationSample) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.samples[r.next] = s
+	r.next = (r.next + 1) % len(r.samples)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+func (r *PopulationRing) Samples(since time.Time) []PopulationSample {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	ordered := r.samples[:r.next]
+	if r.full {
+		ordered = append(append([]PopulationSample{}, r.samples[r.next:]...), r.samples[:r.next]...)
+	}
+	samples := make([]PopulationSample, 0, len(ordered))
+	for _, s := range ordered {
+		if !s.Time.Before(since) {
+			samples = append(samples, s)
+		}
+	}
+	return samples
+}
+
+// Population figures for a single hour.
+type PopulationStats struct {
+	Hour           time.Time
+	Samples        int
+	PeakPlayers    uint32
+	AveragePlayers float32
+	PeakQueued     uint32
+	AverageQueued  float32
+}
+
+// Groups samples by hour, oldest first.
+func HourlyPopulation(samples []PopulationSample) []PopulationStats {
+	stats := make([]PopulationStats, 0)
+	var current *PopulationStats
+	var players, queued uint64
+	flush := func() {
+		if current != nil {
+			current.AveragePlayers = float32(players) / float32(current.Samples)
+			current.AverageQueued = float32(queued) / float32(current.Samples)
+			stats = append(stats, *current)
+		}
+	}
+	for _, s := range samples {
+		hour := s.Time.Truncate(time.Hour)
+		if current == nil || !current.Hour.Equal(hour) {
+			flush()
+			current = &PopulationStats{Hour: hour}
+			players, queued = 0, 0
+		}
+		current.Samples++
+		players += uint64(s.Players)
+		queued += uint64(s.Queued)
+		if s.Players > current.PeakPlayers {
+			current.PeakPlayers = s.Players
+		}
+		if s.Queued > current.PeakQueued {
+			current.PeakQueued = s.Queued
+		}
+	}
+	flush()
+	return stats
+}
+
+type PopulationMetric int
+
+const (
+	MetricPlayers PopulationMetric = iota
+	MetricQueued
+)
+
+// A population level worth knowing about, e.g. "queue above 50" or "players below 20".
+type PopulationThreshold struct {
+	Metric PopulationMetric
+	// True to trigger when the metric rises above the value, false to trigger when it drops below.
+	Above bool
+	Value uint32
+}
+
+func (t PopulationThreshold) reached(s PopulationSample) bool {
+	value := s.Players
+	if t.Metric == MetricQueued {
+		value = s.Queued
+	}
+	if t.Above {
+		return value > t.Value
+	}
+	return value < t.Value
+}
+
+type PopulationEvent func(t PopulationThreshold, s PopulationSample)
+
+type populationWatch struct {
+	threshold PopulationThreshold
+	callback  PopulationEvent
+	triggered bool
+}
+
+// Polls the server info, recording the population over time and raising events when thresholds are crossed.
+type PopulationTracker struct {
+	client  *Client
+	store   PopulationStore
+	lock    sync.Mutex
+	latest  *PopulationSample
+	watches map[uint32]*populationWatch
+	hSeq    uint32
+	quit    chan struct{}
+	once    sync.Once
+}
+
+// Creates a tracker that records into the given store. A nil store keeps a day of samples in memory.
+func NewPopulationTracker(c *Client, store PopulationStore) *PopulationTracker {
+	if store == nil {
+		store = NewPopulationRing(DefaultPopulationHistory)
+	}
+	return &PopulationTracker{
+		client:  c,
+		store:   store,
+		watches: make(map[uint32]*populationWatch),
+		quit:    make(chan struct{}),
+	}
+}
+
+// The store samples are recorded into.
+func (p *PopulationTracker) Store() PopulationStore {
+	return p.store
+}
+
+// Registers a function to be called each time the threshold is crossed. It will not be called again
+// until the population has gone back the other way.
+func (p *PopulationTracker) AddThreshold(t PopulationThreshold, f PopulationEvent) uint32 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.hSeq++
+	p.watches[p.hSeq] = &populationWatch{threshold: t, callback: f}
+	return p.hSeq
+}
+
+// Removes a threshold.
+func (p *PopulationTracker) RemoveThreshold(i uint32) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.watches[i]; ok {
+		delete(p.watches, i)
+		return nil
+	}
+	return fmt.Errorf("threshold id %d is not registered", i)
+}
+
+// Starts polling the server info in the background.
+func (p *PopulationTracker) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		p.Poll()
+		for {
+			select {
+			case <-p.quit:
+				return
+			case <-ticker.C:
+				p.Poll()
+			}
+		}
+	}()
+}
+
+// Stops polling.
+func (p *PopulationTracker) Stop() {
+	p.once.Do(func() {
+		close(p.quit)
+	})
+}
+
+// Requests the server info once. The sample is recorded when the response arrives.
+func (p *PopulationTracker) Poll() error {
+	return p.client.GetServerInfo(p.Record)
+}
+
+// Records a sample from a GetInfo response and checks the thresholds.
+func (p *PopulationTracker) Record(info *AppInfo) {
+	s := newPopulationSample(info, time.Now())
+	p.store.Add(s)
+
+	p.lock.Lock()
+	p.latest = &s
+	fired := make([]*populationWatch, 0)
+	for _, w := range p.watches {
+		reached := w.threshold.reached(s)
+		if reached && !w.triggered {
+			fired = append(fired, w)
+		}
+		w.triggered = reached
+	}
+	p.lock.Unlock()
+
+	for _, w := range fired {
+		w.callback(w.threshold, s)
+	}
+}
+
+// The most recent sample.
+func (p *PopulationTracker) Latest() (PopulationSample, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.latest == nil {
+		return PopulationSample{}, false
+	}
+	return *p.latest, true
+}
+
+// Hourly peak and average population since the given time.
+func (p *PopulationTracker) HourlyStats(since time.Time) []PopulationStats {
+	return HourlyPopulation(p.store.Samples(since))
+}
@@ -1,18 +1,27 @@
 package rustplus
 
-import "errors"
+import (
+	"errors"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
 
 // A set of helper functions for building requests. Execute these requests using 'Client.Write'.
 
-// Builds a base request.
+// Builds a base request, signed with a token picked by the client's TokenPolicy.
 func (c *Client) NewRequest() (*AppRequest, error) {
-	if c.connection == nil {
+	return c.NewRequestAs(0)
+}
+
+// Builds a base request signed with the given player's token. Falls back to any working token if the player has none.
+func (c *Client) NewRequestAs(steamId uint64) (*AppRequest, error) {
+	if !c.Connected() {
 		return nil, errors.New("connection is nil")
 	}
-	if len(c.connectionData.Tokens) == 0 {
-		return nil, errors.New("no tokens")
+	token, err := c.pickToken(steamId)
+	if err != nil {
+		return nil, err
 	}
-	token := c.connectionData.Tokens[0]
 
 	seq := c.GetSeq()
 	request := AppRequest{
@@ -23,8 +32,9 @@ func (c *Client) NewRequest() (*AppRequest, error) {
 	return &request, nil
 }
 
+// Device requests are signed with the token of the player that paired the device, when known.
 func (c *Client) NewDeviceGetRequest(device *Device) (*AppRequest, error) {
-	req, err := c.NewRequest()
+	req, err := c.NewRequestAs(device.Owner)
 	if err != nil {
 		return nil, err
 	}
@@ -35,7 +45,7 @@ func (c *Client) NewDeviceGetRequest(device *Device) (*AppRequest, error) {
 }
 
 func (c *Client) NewDeviceSetRequest(device *Device, state bool) (*AppRequest, error) {
-	req, err := c.NewRequest()
+	req, err := c.NewRequestAs(device.Owner)
 	if err != nil {
 		return nil, err
 	}
@@ -125,3 +135,22 @@ func (c *Client) NewPromoteRequest(id uint64) (*AppRequest, error) {
 	req.PromoteToLeader = &AppPromoteToLeader{SteamId: &id}
 	return req, nil
 }
+
+// Builds a request from its protojson form, e.g. {"getTime": {}}. The seq is always filled in, and the player token
+// when the JSON leaves it out.
+func (c *Client) NewRawRequest(data []byte) (*AppRequest, error) {
+	req := &AppRequest{}
+	// seq, playerId and playerToken are required fields, so allow them to be left out.
+	if err := (protojson.UnmarshalOptions{AllowPartial: true}).Unmarshal(data, req); err != nil {
+		return nil, err
+	}
+	base, err := c.NewRequest()
+	if err != nil {
+		return nil, err
+	}
+	req.Seq = base.Seq
+	if req.PlayerId == nil || req.PlayerToken == nil {
+		req.PlayerId, req.PlayerToken = base.PlayerId, base.PlayerToken
+	}
+	return req, nil
+}
@@ -0,0 +1,31 @@
+package rustplus
+
+import "testing"
+
+func TestNewRawRequest(t *testing.T) {
+	c := handshakeClient(t, goodPlayer)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Disconnect()
+
+	req, err := c.NewRawRequest([]byte(`{"getTime": {}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Seq == nil || req.GetTime == nil || req.GetPlayerId() != goodPlayer || req.PlayerToken == nil {
+		t.Errorf("got %v, want a signed getTime request", req)
+	}
+
+	next, err := c.NewRawRequest([]byte(`{"seq": 0, "playerId": "7", "playerToken": 8, "getInfo": {}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.GetSeq() == req.GetSeq() || next.GetPlayerId() != 7 || next.GetPlayerToken() != 8 {
+		t.Errorf("got %v, want a fresh seq and player 7's token", next)
+	}
+
+	if _, err := c.NewRawRequest([]byte(`{"getTime": `)); err == nil {
+		t.Error("malformed request was accepted")
+	}
+}
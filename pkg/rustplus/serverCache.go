@@ -0,0 +1,114 @@
+package rustplus
+
+import (
+	"fmt"
+	"sync"
+)
+
+type WipeEvent func(previous *AppInfo, current *AppInfo)
+
+// Identifies a single wipe of a server. The map only changes when one of these does.
+type wipeKey struct {
+	seed     uint32
+	salt     uint32
+	wipeTime uint32
+	mapSize  uint32
+}
+
+func newWipeKey(info *AppInfo) wipeKey {
+	return wipeKey{info.GetSeed(), info.GetSalt(), info.GetWipeTime(), info.GetMapSize()}
+}
+
+// Holds the latest server info and map, so the multi-megabyte map image is only downloaded once per wipe.
+type serverCache struct {
+	lock     sync.Mutex
+	info     *AppInfo
+	mapData  *AppMap
+	mapKey   wipeKey
+	handlers map[uint32]WipeEvent
+	hSeq     uint32
+}
+
+// Registers a function to be called when the server is found to have wiped.
+func (c *Client) AddWipeEvent(f WipeEvent) uint32 {
+	c.cache.lock.Lock()
+	defer c.cache.lock.Unlock()
+	if c.cache.handlers == nil {
+		c.cache.handlers = make(map[uint32]WipeEvent)
+	}
+	c.cache.hSeq++
+	c.cache.handlers[c.cache.hSeq] = f
+	return c.cache.hSeq
+}
+
+// Removes a wipe event.
+func (c *Client) RemoveWipeEvent(i uint32) error {
+	c.cache.lock.Lock()
+	defer c.cache.lock.Unlock()
+	if _, ok := c.cache.handlers[i]; ok {
+		delete(c.cache.handlers, i)
+		return nil
+	}
+	return fmt.Errorf("event id %d is not registered", i)
+}
+
+// The most recent server info, or nil if none has been requested yet.
+func (c *Client) CachedInfo() *AppInfo {
+	c.cache.lock.Lock()
+	defer c.cache.lock.Unlock()
+	return c.cache.info
+}
+
+// The cached map, or nil if it has not been downloaded this wipe.
+func (c *Client) CachedMap() *AppMap {
+	c.cache.lock.Lock()
+	defer c.cache.lock.Unlock()
+	return c.cache.mapData
+}
+
+// Forgets the cached info and map, forcing the next requests to go to the server.
+func (c *Client) ClearCache() {
+	c.cache.lock.Lock()
+	defer c.cache.lock.Unlock()
+	c.cache.info = nil
+	c.cache.mapData = nil
+}
+
+// Records fresh server info, dropping the cached map and firing wipe events if the server has wiped.
+func (c *Client) updateInfo(info *AppInfo) {
+	c.cache.lock.Lock()
+	previous := c.cache.info
+	c.cache.info = info
+	wiped := previous != nil && newWipeKey(previous) != newWipeKey(info)
+	if c.cache.mapData != nil && c.cache.mapKey != newWipeKey(info) {
+		c.cache.mapData = nil
+	}
+	handlers := make([]WipeEvent, 0, len(c.cache.handlers))
+	for _, f := range c.cache.handlers {
+		handlers = append(handlers, f)
+	}
+	c.cache.lock.Unlock()
+
+	if wiped {
+		for _, f := range handlers {
+			f(previous, info)
+		}
+	}
+}
+
+// Returns the cached map if it belongs to the current wipe.
+func (c *Client) mapFor(info *AppInfo) *AppMap {
+	c.cache.lock.Lock()
+	defer c.cache.lock.Unlock()
+	if c.cache.mapData != nil && c.cache.mapKey == newWipeKey(info) {
+		return c.cache.mapData
+	}
+	return nil
+}
+
+func (c *Client) storeMap(info *AppInfo, m *AppMap) {
+	c.cache.lock.Lock()
+	defer c.cache.lock.Unlock()
+	c.cache.mapData = m
+	c.cache.mapKey = newWipeKey(info)
+}
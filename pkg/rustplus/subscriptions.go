@@ -0,0 +1,235 @@
+package rustplus
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// What a subscription does with a new event when its queue is full.
+type OverflowPolicy int
+
+const (
+	// Discard the oldest queued event to make room for the new one.
+	DropOldest OverflowPolicy = iota
+	// Discard the new event.
+	DropNewest
+	// Wait for the subscriber to make room. This stalls every other subscriber and device callback, use with caution!
+	Block
+)
+
+type subscriber interface {
+	deliver(v interface{})
+}
+
+// Fans events out to any number of subscribers.
+type broker struct {
+	lock sync.RWMutex
+	seq  uint32
+	subs map[uint32]subscriber
+}
+
+func (b *broker) add(s subscriber) uint32 {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.subs == nil {
+		b.subs = make(map[uint32]subscriber)
+	}
+	b.seq++
+	b.subs[b.seq] = s
+	return b.seq
+}
+
+func (b *broker) remove(id uint32) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.subs, id)
+}
+
+func (b *broker) publish(v interface{}) {
+	b.lock.RLock()
+	subs := make([]subscriber, 0, len(b.subs))
+	for _, s := range b.subs {
+		subs = append(subs, s)
+	}
+	b.lock.RUnlock()
+
+	for _, s := range subs {
+		s.deliver(v)
+	}
+}
+
+// Shared bookkeeping for the typed subscriptions below.
+type subscription struct {
+	dropped uint64 // Kept first for 64-bit alignment.
+	id      uint32
+	broker  *broker
+	policy  OverflowPolicy
+	lock    sync.Mutex
+	done    chan struct{}
+	once    sync.Once
+	closed  bool
+}
+
+func newSubscription(b *broker, policy OverflowPolicy) subscription {
+	return subscription{broker: b, policy: policy, done: make(chan struct{})}
+}
+
+// Number of events discarded because the queue was full.
+func (s *subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Stops delivery and closes the channel once the subscriber has been removed.
+func (s *subscription) close(closeChannel func()) {
+	s.once.Do(func() {
+		s.broker.remove(s.id)
+		// Release any delivery blocked on a full queue before taking the lock.
+		close(s.done)
+		s.lock.Lock()
+		s.closed = true
+		closeChannel()
+		s.lock.Unlock()
+	})
+}
+
+//====================================================================================
+//============================== Chat Subscription ===================================
+//====================================================================================
+
+// An independent, buffered feed of team chat messages.
+type ChatSubscription struct {
+	subscription
+	ch chan *AppChatMessage
+	C  <-chan *AppChatMessage
+}
+
+func (s *ChatSubscription) deliver(v interface{}) {
+	m := v.(*AppChatMessage)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return
+	}
+	switch s.policy {
+	case Block:
+		select {
+		case s.ch <- m:
+		case <-s.done:
+		}
+	case DropNewest:
+		select {
+		case s.ch <- m:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	default:
+		for {
+			select {
+			case s.ch <- m:
+				return
+			default:
+			}
+			select {
+			case <-s.ch:
+				atomic.AddUint64(&s.dropped, 1)
+			default:
+			}
+		}
+	}
+}
+
+// Unsubscribes and closes C.
+func (s *ChatSubscription) Close() {
+	s.close(func() { close(s.ch) })
+}
+
+//====================================================================================
+//============================== Team Subscription ===================================
+//====================================================================================
+
+// An independent, buffered feed of team changes.
+type TeamSubscription struct {
+	subscription
+	ch chan *AppTeamChanged
+	C  <-chan *AppTeamChanged
+}
+
+func (s *TeamSubscription) deliver(v interface{}) {
+	m := v.(*AppTeamChanged)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return
+	}
+	switch s.policy {
+	case Block:
+		select {
+		case s.ch <- m:
+		case <-s.done:
+		}
+	case DropNewest:
+		select {
+		case s.ch <- m:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	default:
+		for {
+			select {
+			case s.ch <- m:
+				return
+			default:
+			}
+			select {
+			case <-s.ch:
+				atomic.AddUint64(&s.dropped, 1)
+			default:
+			}
+		}
+	}
+}
+
+// Unsubscribes and closes C.
+func (s *TeamSubscription) Close() {
+	s.close(func() { close(s.ch) })
+}
+
+//====================================================================================
+//============================== Client Functions ====================================
+//====================================================================================
+
+// Subscribes to team chat messages. Each subscriber gets its own queue of the given size (at least one).
+func (c *Client) SubscribeChat(buffer int, policy OverflowPolicy) *ChatSubscription {
+	if buffer < 1 {
+		buffer = 1
+	}
+	ch := make(chan *AppChatMessage, buffer)
+	s := &ChatSubscription{subscription: newSubscription(&c.chatBroker, policy), ch: ch, C: ch}
+	s.id = c.chatBroker.add(s)
+	return s
+}
+
+// Subscribes to team changes. Each subscriber gets its own queue of the given size (at least one).
+func (c *Client) SubscribeTeam(buffer int, policy OverflowPolicy) *TeamSubscription {
+	if buffer < 1 {
+		buffer = 1
+	}
+	ch := make(chan *AppTeamChanged, buffer)
+	s := &TeamSubscription{subscription: newSubscription(&c.teamBroker, policy), ch: ch, C: ch}
+	s.id = c.teamBroker.add(s)
+	return s
+}
+
+func (c *Client) publishChat(m *AppChatMessage) {
+	c.chatBroker.publish(m)
+	if c.Chat != nil {
+		c.Chat <- m
+	}
+}
+
+func (c *Client) publishTeam(t *AppTeamChanged) {
+	c.teamBroker.publish(t)
+	if c.Team != nil {
+		c.Team <- t
+	}
+}
@@ -0,0 +1,110 @@
+package rustplus
+
+import (
+	"testing"
+	"time"
+)
+
+// Publishes messages "0", "1", ... to the client's chat subscribers.
+func publishMessages(c *Client, n int) {
+	for i := 0; i < n; i++ {
+		c.publishChat(chatMessage(1, uint32(i), string(rune('0'+i))))
+	}
+}
+
+func drain(s *ChatSubscription) string {
+	got := ""
+	for {
+		select {
+		case m := <-s.C:
+			got += m.GetMessage()
+		default:
+			return got
+		}
+	}
+}
+
+func TestSubscriptionOverflow(t *testing.T) {
+	tests := []struct {
+		policy  OverflowPolicy
+		want    string
+		dropped uint64
+	}{
+		{DropOldest, "234", 2},
+		{DropNewest, "012", 2},
+	}
+	for _, test := range tests {
+		c := &Client{}
+		s := c.SubscribeChat(3, test.policy)
+		publishMessages(c, 5)
+		if got := drain(s); got != test.want {
+			t.Errorf("policy %d: got %q, want %q", test.policy, got, test.want)
+		}
+		if s.Dropped() != test.dropped {
+			t.Errorf("policy %d: dropped %d, want %d", test.policy, s.Dropped(), test.dropped)
+		}
+	}
+}
+
+func TestSubscribersAreIndependent(t *testing.T) {
+	c := &Client{}
+	slow := c.SubscribeChat(1, DropOldest)
+	fast := c.SubscribeChat(8, DropNewest)
+	publishMessages(c, 4)
+	if got := drain(slow); got != "3" {
+		t.Errorf("slow subscriber got %q, want %q", got, "3")
+	}
+	if got := drain(fast); got != "0123" || fast.Dropped() != 0 {
+		t.Errorf("fast subscriber got %q with %d dropped, want all of it", got, fast.Dropped())
+	}
+}
+
+func TestBlockingSubscription(t *testing.T) {
+	c := &Client{}
+	s := c.SubscribeChat(1, Block)
+	published := make(chan struct{})
+	go func() {
+		publishMessages(c, 2)
+		close(published)
+	}()
+
+	select {
+	case <-published:
+		t.Fatal("publish did not wait for the full queue")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if m := <-s.C; m.GetMessage() != "0" {
+		t.Errorf("got %q, want %q", m.GetMessage(), "0")
+	}
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("publish was not released once there was room")
+	}
+	if s.Dropped() != 0 {
+		t.Errorf("dropped %d, want 0", s.Dropped())
+	}
+}
+
+func TestCloseReleasesBlockedPublish(t *testing.T) {
+	c := &Client{}
+	s := c.SubscribeChat(1, Block)
+	published := make(chan struct{})
+	go func() {
+		publishMessages(c, 3)
+		close(published)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	s.Close()
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("Close did not release the blocked publish")
+	}
+
+	// The channel is closed once whatever was queued has been read.
+	for range s.C {
+	}
+	publishMessages(c, 1)
+	s.Close()
+}
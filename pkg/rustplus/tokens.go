@@ -0,0 +1,142 @@
+package rustplus
+
+import "errors"
+
+// How the client picks a player token for each request.
+type TokenPolicy int
+
+const (
+	// Use the first working token, moving to the next only when it fails.
+	TokenFailover TokenPolicy = iota
+	// Spread requests across all working tokens in turn.
+	TokenRoundRobin
+)
+
+// Errors returned by the server when a player token is not accepted.
+var authErrors = map[string]bool{
+	"no_player":           true,
+	"access_denied":       true,
+	"invalid_playerid":    true,
+	"invalid_playertoken": true,
+	"banned":              true,
+}
+
+// True if the error is the server rejecting the player token, rather than the request itself.
+func IsAuthError(e *AppError) bool {
+	return e != nil && authErrors[e.GetError()]
+}
+
+// A player token and why it stopped working, if it has.
+type TokenStatus struct {
+	Token PlayerToken
+	// Nil while the token is usable.
+	Err error
+	// True if the server accepted the token during the last connect handshake.
+	Verified bool
+}
+
+// Sets how tokens are picked for new requests.
+func (c *Client) SetTokenPolicy(p TokenPolicy) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.tokenPolicy = p
+}
+
+// Adds a player token to the client, or replaces the token for a player it already has.
+func (c *Client) AddToken(token PlayerToken) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for i, t := range c.connectionData.Tokens {
+		if t.SteamId == token.SteamId {
+			c.connectionData.Tokens[i] = token
+			delete(c.badTokens, token.SteamId)
+			return
+		}
+	}
+	c.connectionData.AddToken(token)
+}
+
+// Every token the client knows, in order, with any error that took it out of use.
+func (c *Client) Tokens() []TokenStatus {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	status := make([]TokenStatus, len(c.connectionData.Tokens))
+	for i, t := range c.connectionData.Tokens {
+		status[i] = TokenStatus{Token: t, Err: c.badTokens[t.SteamId], Verified: c.verified[t.SteamId]}
+	}
+	return status
+}
+
+// Takes a token out of use. Requests signed with it are moved to another token.
+func (c *Client) DisableToken(steamId uint64, reason error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.disableToken(steamId, reason)
+}
+
+// Puts every token back in use, e.g. after the tokens have been refreshed.
+func (c *Client) ResetTokens() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.badTokens = make(map[uint64]error)
+}
+
+func (c *Client) disableToken(steamId uint64, reason error) {
+	if reason == nil {
+		reason = errors.New("disabled")
+	}
+	c.badTokens[steamId] = reason
+}
+
+// Picks a token for a request. The preferred player's token is used if it is still working, otherwise the policy decides.
+func (c *Client) pickToken(preferred uint64) (PlayerToken, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	tokens := c.connectionData.Tokens
+	if len(tokens) == 0 {
+		return PlayerToken{}, errors.New("no tokens")
+	}
+	if preferred != 0 {
+		for _, t := range tokens {
+			if t.SteamId == preferred && c.badTokens[t.SteamId] == nil {
+				return t, nil
+			}
+		}
+	}
+	for i := range tokens {
+		n := i
+		if c.tokenPolicy == TokenRoundRobin {
+			n = (c.tokenIndex + i) % len(tokens)
+		}
+		if c.badTokens[tokens[n].SteamId] == nil {
+			c.tokenIndex = n + 1
+			return tokens[n], nil
+		}
+	}
+	return PlayerToken{}, errors.New("no working tokens")
+}
+
+// Called with a response to a request we sent. If the server rejected the token, the token is disabled and the
+// request is sent again with another one. Returns true if the request was resent.
+func (c *Client) failover(r *AppResponse) bool {
+	if !IsAuthError(r.Error) {
+		return false
+	}
+	c.lock.Lock()
+	req := c.pending[r.GetSeq()]
+	if req == nil {
+		c.lock.Unlock()
+		return false
+	}
+	c.disableToken(req.GetPlayerId(), &rejectedError{r.Error.GetError()})
+	delete(c.verified, req.GetPlayerId())
+	c.lock.Unlock()
+
+	token, err := c.pickToken(0)
+	if err != nil {
+		return false
+	}
+	req.PlayerId = &token.SteamId
+	req.PlayerToken = &token.Token
+	return c.send(req) == nil
+}
@@ -0,0 +1,111 @@
+package rustplus
+
+import (
+	"math"
+	"time"
+)
+
+// Default number of positions kept for each moving marker.
+const DefaultTrajectoryLength = 10
+
+// A marker position seen at a point in time.
+type TrajectorySample struct {
+	X        float32
+	Y        float32
+	Rotation float32
+	Time     time.Time
+}
+
+// Recent positions of a moving marker such as the cargo ship or a chinook, oldest first.
+type Trajectory struct {
+	Id      uint32
+	Type    AppMarkerType
+	Samples []TrajectorySample
+}
+
+func (tr *Trajectory) add(m *AppMarker, now time.Time, length int) {
+	tr.Samples = append(tr.Samples, TrajectorySample{X: m.GetX(), Y: m.GetY(), Rotation: m.GetRotation(), Time: now})
+	if length > 0 && len(tr.Samples) > length {
+		tr.Samples = tr.Samples[len(tr.Samples)-length:]
+	}
+}
+
+func (tr *Trajectory) copy() *Trajectory {
+	samples := make([]TrajectorySample, len(tr.Samples))
+	copy(samples, tr.Samples)
+	return &Trajectory{Id: tr.Id, Type: tr.Type, Samples: samples}
+}
+
+// The most recent sample.
+func (tr *Trajectory) Latest() TrajectorySample {
+	return tr.Samples[len(tr.Samples)-1]
+}
+
+// Estimated velocity in world units per second. Zero until there are at least two samples.
+func (tr *Trajectory) Velocity() (float32, float32) {
+	if len(tr.Samples) < 2 {
+		return 0, 0
+	}
+	first, last := tr.Samples[0], tr.Latest()
+	seconds := float32(last.Time.Sub(first.Time).Seconds())
+	if seconds <= 0 {
+		return 0, 0
+	}
+	return (last.X - first.X) / seconds, (last.Y - first.Y) / seconds
+}
+
+// Estimated speed in world units per second.
+func (tr *Trajectory) Speed() float32 {
+	vx, vy := tr.Velocity()
+	return float32(math.Hypot(float64(vx), float64(vy)))
+}
+
+// Estimated heading in degrees clockwise from north. Falls back to the reported rotation while the marker is not moving.
+func (tr *Trajectory) Heading() float32 {
+	vx, vy := tr.Velocity()
+	if vx == 0 && vy == 0 {
+		return tr.Latest().Rotation
+	}
+	heading := math.Atan2(float64(vx), float64(vy)) * 180 / math.Pi
+	if heading < 0 {
+		heading += 360
+	}
+	return float32(heading)
+}
+
+// Predicts the position after the given time, assuming the marker keeps its current course and speed.
+func (tr *Trajectory) Predict(d time.Duration) (float32, float32) {
+	vx, vy := tr.Velocity()
+	latest := tr.Latest()
+	seconds := float32(time.Since(latest.Time).Seconds() + d.Seconds())
+	return latest.X + vx*seconds, latest.Y + vy*seconds
+}
+
+// How close the marker will come to a world position on its current course, and how long until it gets there.
+// If it is moving away, or not moving at all, this is its current distance with a time of zero.
+func (tr *Trajectory) ClosestApproach(x, y float32) (float32, time.Duration) {
+	vx, vy := tr.Velocity()
+	latest := tr.Latest()
+	dx, dy := x-latest.X, y-latest.Y
+	speed2 := vx*vx + vy*vy
+	if speed2 == 0 {
+		return float32(math.Hypot(float64(dx), float64(dy))), 0
+	}
+	// Time along the course at which we are closest to the point.
+	t := (dx*vx + dy*vy) / speed2
+	if t <= 0 {
+		return float32(math.Hypot(float64(dx), float64(dy))), 0
+	}
+	cx, cy := latest.X+vx*t-x, latest.Y+vy*t-y
+	eta := time.Duration(float64(t)*float64(time.Second)) - time.Since(latest.Time)
+	if eta < 0 {
+		eta = 0
+	}
+	return float32(math.Hypot(float64(cx), float64(cy))), eta
+}
+
+// True if the marker's current course takes it within the radius of a world position, such as our base.
+func (tr *Trajectory) HeadingToward(x, y, radius float32) bool {
+	distance, eta := tr.ClosestApproach(x, y)
+	return eta > 0 && distance <= radius
+}
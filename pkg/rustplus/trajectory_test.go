@@ -0,0 +1,103 @@
+package rustplus
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// A trajectory whose samples are the given positions one second apart, the last taken now.
+func trajectory(positions ...[2]float32) *Trajectory {
+	tr := &Trajectory{Id: 1, Type: AppMarkerType_CargoShip}
+	start := time.Now().Add(-time.Duration(len(positions)-1) * time.Second)
+	for i, p := range positions {
+		tr.Samples = append(tr.Samples, TrajectorySample{X: p[0], Y: p[1], Rotation: 45, Time: start.Add(time.Duration(i) * time.Second)})
+	}
+	return tr
+}
+
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func TestTrajectoryHeading(t *testing.T) {
+	tests := []struct {
+		name    string
+		tr      *Trajectory
+		speed   float32
+		heading float32
+	}{
+		{"east", trajectory([2]float32{0, 0}, [2]float32{10, 0}, [2]float32{20, 0}), 10, 90},
+		{"north", trajectory([2]float32{0, 0}, [2]float32{0, 5}), 5, 0},
+		{"south west", trajectory([2]float32{3, 3}, [2]float32{0, 0}), float32(math.Sqrt(18)), 225},
+		{"stationary", trajectory([2]float32{7, 7}, [2]float32{7, 7}), 0, 45},
+		{"one sample", trajectory([2]float32{7, 7}), 0, 45},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.tr.Speed(); !near(float64(got), float64(test.speed), 0.01) {
+				t.Errorf("got speed %f, want %f", got, test.speed)
+			}
+			if got := test.tr.Heading(); !near(float64(got), float64(test.heading), 0.01) {
+				t.Errorf("got heading %f, want %f", got, test.heading)
+			}
+		})
+	}
+}
+
+func TestTrajectoryPredict(t *testing.T) {
+	tr := trajectory([2]float32{0, 0}, [2]float32{10, 0}, [2]float32{20, 0})
+	x, y := tr.Predict(5 * time.Second)
+	// A little extra time passes between building the trajectory and predicting.
+	if !near(float64(x), 70, 1) || y != 0 {
+		t.Errorf("predicted (%f, %f), want (70, 0)", x, y)
+	}
+}
+
+func TestTrajectoryClosestApproach(t *testing.T) {
+	tr := trajectory([2]float32{0, 0}, [2]float32{10, 0}, [2]float32{20, 0})
+	tests := []struct {
+		name     string
+		x, y     float32
+		distance float32
+		eta      time.Duration
+	}{
+		{"ahead", 420, 30, 30, 40 * time.Second},
+		{"on course", 120, 0, 0, 10 * time.Second},
+		{"behind", -80, 0, 100, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			distance, eta := tr.ClosestApproach(test.x, test.y)
+			if !near(float64(distance), float64(test.distance), 0.01) {
+				t.Errorf("got distance %f, want %f", distance, test.distance)
+			}
+			if !near(eta.Seconds(), test.eta.Seconds(), 0.5) {
+				t.Errorf("got eta %s, want %s", eta, test.eta)
+			}
+		})
+	}
+
+	if !tr.HeadingToward(420, 30, 50) {
+		t.Error("not heading toward a point it passes within the radius")
+	}
+	if tr.HeadingToward(420, 30, 10) {
+		t.Error("heading toward a point it passes outside the radius")
+	}
+	if tr.HeadingToward(-80, 0, 500) {
+		t.Error("heading toward a point behind it")
+	}
+}
+
+func TestTrajectoryLength(t *testing.T) {
+	tr := &Trajectory{}
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		tr.add(&AppMarker{X: proto.Float32(float32(i)), Y: proto.Float32(0)}, now.Add(time.Duration(i)*time.Second), 3)
+	}
+	if len(tr.Samples) != 3 || tr.Samples[0].X != 2 || tr.Latest().X != 4 {
+		t.Errorf("got samples %+v, want the last three", tr.Samples)
+	}
+}
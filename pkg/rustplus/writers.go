@@ -1,5 +1,10 @@
 package rustplus
 
+import (
+	"errors"
+	"fmt"
+)
+
 // Helper functions to handle both request building and writing, with callback support.
 // Use the simple request functions if you require greater finesse in your callbacks!
 
@@ -37,28 +42,102 @@ func (c *Client) SetDeviceInfo(d *Device, state bool, callback DeviceCallbackFun
 	return nil
 }
 
+// Requests the server info. Every response is checked for a wipe, see AddWipeEvent.
 func (c *Client) GetServerInfo(callback func(info *AppInfo)) error {
-	request, err := c.NewMapRequest()
+	request, err := c.NewInfoRequest()
 	if err != nil {
 		return err
 	}
-	cb := NewServerCb(callback)
+	cb := NewServerCb(func(info *AppInfo) {
+		c.updateInfo(info)
+		if callback != nil {
+			callback(info)
+		}
+	})
+	return c.Write(request, cb)
+}
+
+// Requests the map. The server info is checked first, and the map is only downloaded if it has changed since the last call.
+// The callback is given either the map or the error that stopped it being fetched, including errors from the server.
+func (c *Client) GetMapInfo(callback func(data *AppMap, err error)) error {
+	if callback == nil {
+		return errors.New("map callback is nil")
+	}
+	request, err := c.NewInfoRequest()
 	if err != nil {
 		return err
 	}
-	c.Write(request, cb)
-	return nil
+	cb := NewPrimitiveCb(func(m *AppResponse) {
+		if m.Error != nil {
+			callback(nil, fmt.Errorf("server info: %s", m.Error.GetError()))
+			return
+		}
+		if m.Info == nil {
+			callback(nil, errors.New("server info: empty response"))
+			return
+		}
+		c.updateInfo(m.Info)
+		c.fetchMap(m.Info, callback)
+	})
+	return c.Write(request, cb)
 }
 
-func (c *Client) GetMapInfo(callback func(data *AppMap)) error {
+// Hands the cached map for the server to the callback, downloading it if there is none.
+func (c *Client) fetchMap(info *AppInfo, callback func(data *AppMap, err error)) {
+	if m := c.mapFor(info); m != nil {
+		callback(m, nil)
+		return
+	}
 	request, err := c.NewMapRequest()
+	if err != nil {
+		callback(nil, err)
+		return
+	}
+	cb := NewPrimitiveCb(func(m *AppResponse) {
+		if m.Error != nil {
+			callback(nil, fmt.Errorf("map: %s", m.Error.GetError()))
+			return
+		}
+		if m.Map == nil {
+			callback(nil, errors.New("map: empty response"))
+			return
+		}
+		c.storeMap(info, m.Map)
+		callback(m.Map, nil)
+	})
+	if err := c.Write(request, cb); err != nil {
+		callback(nil, err)
+	}
+}
+
+func (c *Client) GetTeamChat(callback func(chat *AppTeamChat)) error {
+	request, err := c.NewChatReadRequest()
 	if err != nil {
 		return err
 	}
-	cb := NewMapCb(callback)
+	return c.Write(request, NewChatCb(callback))
+}
+
+func (c *Client) GetTime(callback func(t *AppTime)) error {
+	request, err := c.NewTimeRequest()
 	if err != nil {
 		return err
 	}
-	c.Write(request, cb)
-	return nil
+	return c.Write(request, NewTimeCb(callback))
+}
+
+func (c *Client) GetMapMarkers(callback func(markers *AppMapMarkers)) error {
+	request, err := c.NewMarkersRequest()
+	if err != nil {
+		return err
+	}
+	return c.Write(request, NewMarkersCb(callback))
+}
+
+func (c *Client) GetCameraFrame(id string, frame uint32, callback func(frame *AppCameraFrame)) error {
+	request, err := c.NewCameraRequest(id, frame)
+	if err != nil {
+		return err
+	}
+	return c.Write(request, NewCameraCb(callback))
 }